@@ -3,11 +3,18 @@ package bitbucket
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Error represents a error from the bitbucket api.
@@ -17,37 +24,238 @@ type Error struct {
 	} `json:"error,omitempty"`
 	Type       string `json:"type,omitempty"`
 	StatusCode int
+	Method     string
 	Endpoint   string
 }
 
 func (e Error) Error() string {
-	return fmt.Sprintf("API Error: %d %s %s", e.StatusCode, e.Endpoint, e.APIError.Message)
+	return fmt.Sprintf("API Error: %d %s %s %s", e.StatusCode, e.Method, e.Endpoint, e.APIError.Message)
+}
+
+// parseError turns an unsuccessful response into an Error with the message of the error bitbucket
+// sent, or the whole body when it isn't one
+func parseError(resp *http.Response) error {
+	apiError := Error{
+		StatusCode: resp.StatusCode,
+	}
+
+	if resp.Request != nil {
+		apiError.Method = resp.Request.Method
+		apiError.Endpoint = strings.TrimPrefix(resp.Request.URL.RequestURI(), "/")
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Resp Body: %s", string(body))
+
+	err = json.Unmarshal(body, &apiError)
+	if err != nil {
+		apiError.APIError.Message = string(body)
+	}
+
+	return apiError
 }
 
 const (
-	// BitbucketEndpoint is the fqdn used to talk to bitbucket
+	// BitbucketEndpoint is the fqdn used to talk to bitbucket unless the client has a BaseURL
 	BitbucketEndpoint string = "https://api.bitbucket.org/"
+	// OAuthTokenEndpoint is where OAuth consumers exchange their key and secret for an access token
+	OAuthTokenEndpoint string = "https://bitbucket.org/site/oauth2/access_token"
+	// APIVersion is the version of the api endpoints are relative to unless they name one themselves
+	APIVersion string = "2.0"
 )
 
+// versionedEndpoint prefixes an endpoint with the api version. Callers leave the version out of 2.0
+// endpoints and only spell out 1.0 for the few things, like groups, that are only in the 1.0 api.
+// Endpoints that already start with a version, like next links, are left alone.
+func versionedEndpoint(endpoint string) string {
+	if strings.HasPrefix(endpoint, "1.0/") || strings.HasPrefix(endpoint, "2.0/") {
+		return endpoint
+	}
+	return APIVersion + "/" + endpoint
+}
+
 // Client is the base internal Client to talk to bitbuckets API. This should be a username and password
 // the password should be a app-password.
 type Client struct {
-	Username   string
-	Password   string
-	HTTPClient *http.Client
+	Username string
+	Password string
+	// Token is sent as a bearer token instead of the username and password when set
+	Token string
+	// BaseURL replaces BitbucketEndpoint when set, e.g. to go through a proxy
+	BaseURL string
+	// DryRun logs requests that would change anything instead of sending them, reads still go out
+	DryRun bool
+	// PageSize is the pagelen GetPaged asks for, bitbucket picks one when it is 0
+	PageSize int
+	// OAuthClientID and OAuthClientSecret are exchanged for a bearer token with the client credentials
+	// grant when set, the token is cached until it expires
+	OAuthClientID     string
+	OAuthClientSecret string
+	HTTPClient        *http.Client
+
+	oauthMutex  sync.Mutex
+	oauthToken  string
+	oauthExpiry time.Time
+
+	// MaxRetries is how many times a rate limited or temporarily unavailable request is retried
+	MaxRetries int
+	// RetryBaseDelay is the delay before the first retry, it doubles on every following attempt
+	RetryBaseDelay time.Duration
+
+	statsMutex sync.Mutex
+	stats      ClientStats
+
+	// CheckScopes makes resources check the scopes of the credentials before they are created
+	CheckScopes bool
+
+	scopesOnce sync.Once
+	scopes     map[string]bool
+	scopesErr  error
+}
+
+// ClientStats counts the requests a Client sent, to help tune concurrency and retries on large applies
+type ClientStats struct {
+	Requests    int
+	Retries     int
+	RateLimited int
+}
+
+// baseURL is the url endpoints are relative to, always ending in a slash
+func (c *Client) baseURL() string {
+	if c.BaseURL == "" {
+		return BitbucketEndpoint
+	}
+	return strings.TrimSuffix(c.BaseURL, "/") + "/"
+}
+
+// grantedScopes looks up the scopes bitbucket reports for the credentials once and caches them, they
+// are nil when bitbucket doesn't report any, which it only does for OAuth tokens
+func (c *Client) grantedScopes() (map[string]bool, error) {
+	c.scopesOnce.Do(func() {
+		resp, err := c.Get("user")
+		if resp != nil {
+			defer resp.Body.Close()
+		}
+		if err != nil {
+			c.scopesErr = err
+			return
+		}
+
+		header := resp.Header.Get("X-OAuth-Scopes")
+		if header == "" {
+			return
+		}
+
+		c.scopes = make(map[string]bool)
+		for _, scope := range strings.Split(header, ",") {
+			c.scopes[strings.TrimSpace(scope)] = true
+		}
+	})
+
+	return c.scopes, c.scopesErr
+}
+
+// Stats returns a snapshot of the request counters
+func (c *Client) Stats() ClientStats {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+	return c.stats
+}
+
+func (c *Client) recordResponse(resp *http.Response, retried bool) {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+
+	c.stats.Requests++
+	if retried {
+		c.stats.Retries++
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		c.stats.RateLimited++
+	}
 }
 
 // Do Will just call the bitbucket api but also add auth to it and some extra headers
 func (c *Client) Do(method, endpoint string, payload *bytes.Buffer) (*http.Response, error) {
+	return c.do(method, endpoint, "application/json", payload)
+}
 
-	absoluteendpoint := BitbucketEndpoint + endpoint
+func (c *Client) do(method, endpoint, contentType string, payload *bytes.Buffer) (*http.Response, error) {
+
+	endpoint = versionedEndpoint(endpoint)
+	absoluteendpoint := c.baseURL() + endpoint
 	log.Printf("[DEBUG] Sending request to %s %s", method, absoluteendpoint)
 
-	var bodyreader io.Reader
+	var body []byte
 
 	if payload != nil {
 		log.Printf("[DEBUG] With payload %s", payload.String())
-		bodyreader = payload
+		body = payload.Bytes()
+	}
+
+	if c.DryRun && method != "GET" {
+		return dryRunResponse(method, absoluteendpoint, body), nil
+	}
+
+	var resp *http.Response
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		resp, err = c.sendReauthenticating(method, absoluteendpoint, contentType, body)
+		log.Printf("[DEBUG] Resp: %v Err: %v", resp, err)
+		if isTimeout(err) {
+			return nil, fmt.Errorf("%s %s timed out, http_timeout_seconds can give bitbucket longer: %w", method, endpoint, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		c.recordResponse(resp, attempt > 0)
+		logRateLimitHeaders(resp)
+
+		if attempt >= c.MaxRetries || !isRetryableStatus(resp.StatusCode) {
+			break
+		}
+
+		delay := retryDelay(resp, c.RetryBaseDelay*time.Duration(1<<uint(attempt)))
+		stats := c.Stats()
+		log.Printf("[DEBUG] Got %d from %s, retrying in %s (%d/%d)", resp.StatusCode, endpoint, delay, attempt+1, c.MaxRetries)
+		log.Printf("[DEBUG] Requests so far: %d, retries: %d, rate limited: %d", stats.Requests, stats.Retries, stats.RateLimited)
+		resp.Body.Close()
+		time.Sleep(delay)
+	}
+
+	if resp.StatusCode >= 400 || resp.StatusCode < 200 {
+		return resp, parseError(resp)
+	}
+	return resp, err
+}
+
+// sendReauthenticating sends the request again with a new OAuth access token when bitbucket rejects
+// the cached one, a token can be revoked or rotated before it expires
+func (c *Client) sendReauthenticating(method, absoluteendpoint, contentType string, body []byte) (*http.Response, error) {
+	resp, err := c.send(method, absoluteendpoint, contentType, body)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || c.Token != "" || c.OAuthClientID == "" {
+		return resp, err
+	}
+
+	log.Printf("[DEBUG] The OAuth access token was rejected by %s %s, requesting a new one", method, absoluteendpoint)
+	resp.Body.Close()
+	c.invalidateOAuthToken(strings.TrimPrefix(resp.Request.Header.Get("Authorization"), "Bearer "))
+
+	return c.send(method, absoluteendpoint, contentType, body)
+}
+
+// send builds and sends a single request, the body is passed as bytes so it can be replayed on a retry
+func (c *Client) send(method, absoluteendpoint, contentType string, body []byte) (*http.Response, error) {
+	var bodyreader io.Reader
+
+	if body != nil {
+		bodyreader = bytes.NewReader(body)
 	}
 
 	req, err := http.NewRequest(method, absoluteendpoint, bodyreader)
@@ -55,39 +263,152 @@ func (c *Client) Do(method, endpoint string, payload *bytes.Buffer) (*http.Respo
 		return nil, err
 	}
 
-	req.SetBasicAuth(c.Username, c.Password)
+	switch {
+	case c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	case c.OAuthClientID != "":
+		token, err := c.oauthAccessToken()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	default:
+		req.SetBasicAuth(c.Username, c.Password)
+	}
 
-	if payload != nil {
+	if body != nil {
 		// Can cause bad request when putting default reviews if set.
-		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add("Content-Type", contentType)
 	}
 
 	req.Close = true
 
+	return c.HTTPClient.Do(req)
+}
+
+// isTimeout is whether a request gave up because it took longer than the timeout of the http client
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// oauthTokenResponse is what bitbucket answers a token request with
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// invalidateOAuthToken drops the cached token when it is the one that was rejected. Requests that
+// were rejected with the same token at the same time then only exchange it once, the others find
+// the new one already cached.
+func (c *Client) invalidateOAuthToken(rejected string) {
+	c.oauthMutex.Lock()
+	defer c.oauthMutex.Unlock()
+
+	if c.oauthToken == rejected {
+		c.oauthToken = ""
+	}
+}
+
+// oauthAccessToken returns the cached bearer token, a new one is requested with the client credentials
+// grant when there is none yet or it is about to expire
+func (c *Client) oauthAccessToken() (string, error) {
+	c.oauthMutex.Lock()
+	defer c.oauthMutex.Unlock()
+
+	// Refresh a bit early so a token doesn't expire between here and bitbucket
+	if c.oauthToken != "" && time.Now().Add(time.Minute).Before(c.oauthExpiry) {
+		return c.oauthToken, nil
+	}
+
+	values := url.Values{}
+	values.Set("grant_type", "client_credentials")
+
+	req, err := http.NewRequest("POST", OAuthTokenEndpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(c.OAuthClientID, c.OAuthClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	log.Printf("[DEBUG] Requesting an OAuth access token from %s", OAuthTokenEndpoint)
+
 	resp, err := c.HTTPClient.Do(req)
-	log.Printf("[DEBUG] Resp: %v Err: %v", resp, err)
-	if resp.StatusCode >= 400 || resp.StatusCode < 200 {
-		apiError := Error{
-			StatusCode: resp.StatusCode,
-			Endpoint:   endpoint,
-		}
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
 
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("Failed to get an OAuth access token for client %s: %d %s", c.OAuthClientID, resp.StatusCode, body)
+	}
+
+	var token oauthTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+
+	c.oauthToken = token.AccessToken
+	c.oauthExpiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+
+	return c.oauthToken, nil
+}
+
+// dryRunResponse logs a request the client didn't send and answers it with an empty success, what
+// resources read back from it is made up so the state a dry run leaves behind can't be trusted
+func dryRunResponse(method, absoluteendpoint string, body []byte) *http.Response {
+	log.Printf("[INFO] Dry run, not sending %s %s %s", method, absoluteendpoint, body)
+
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader("{}")),
+	}
+}
+
+// logRateLimitHeaders logs the rate limit headers bitbucket sends, if any
+func logRateLimitHeaders(resp *http.Response) {
+	for name, values := range resp.Header {
+		if strings.HasPrefix(strings.ToLower(name), "x-ratelimit-") {
+			log.Printf("[DEBUG] %s: %s", name, strings.Join(values, ", "))
 		}
+	}
+}
 
-		log.Printf("[DEBUG] Resp Body: %s", string(body))
+// retryDelay is how long to wait before retrying, bitbucket says how long in the Retry-After header
+// of some responses, either in seconds or as a date, otherwise the backoff is used
+func retryDelay(resp *http.Response, backoff time.Duration) time.Duration {
+	retryAfter := resp.Header.Get("Retry-After")
+	if retryAfter == "" {
+		return backoff
+	}
 
-		err = json.Unmarshal(body, &apiError)
-		if err != nil {
-			apiError.APIError.Message = string(body)
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(retryAfter); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
 		}
+		return 0
+	}
 
-		return resp, error(apiError)
+	return backoff
+}
 
+// isRetryableStatus reports whether a response means we were rate limited or bitbucket was temporarily unavailable
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
 	}
-	return resp, err
+	return false
 }
 
 // Get is just a helper method to do but with a GET verb
@@ -100,6 +421,12 @@ func (c *Client) Post(endpoint string, jsonpayload *bytes.Buffer) (*http.Respons
 	return c.Do("POST", endpoint, jsonpayload)
 }
 
+// PostForm is just a helper method to do but with a POST verb and a form encoded body, a few
+// endpoints like src only take forms
+func (c *Client) PostForm(endpoint string, values url.Values) (*http.Response, error) {
+	return c.do("POST", endpoint, "application/x-www-form-urlencoded", bytes.NewBufferString(values.Encode()))
+}
+
 // Put is just a helper method to do but with a PUT verb
 func (c *Client) Put(endpoint string, jsonpayload *bytes.Buffer) (*http.Response, error) {
 	return c.Do("PUT", endpoint, jsonpayload)
@@ -114,3 +441,82 @@ func (c *Client) PutOnly(endpoint string) (*http.Response, error) {
 func (c *Client) Delete(endpoint string) (*http.Response, error) {
 	return c.Do("DELETE", endpoint, nil)
 }
+
+// DeleteIgnoringNotFound deletes the endpoint and treats a 404 as success, child resources are
+// gone already when the repository they belong to was deleted first
+func (c *Client) DeleteIgnoringNotFound(endpoint string) error {
+	resp, err := c.Delete(endpoint)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	return err
+}
+
+// maxPages stops GetPaged following next links forever if the api keeps handing out new ones
+const maxPages = 1000
+
+// GetPaged gets every page of a paginated list endpoint by following the next links and returns
+// the values of all of them, each value is left for the caller to decode
+func (c *Client) GetPaged(endpoint string) ([]json.RawMessage, error) {
+	var values []json.RawMessage
+
+	err := c.VisitPaged(endpoint, func(page []json.RawMessage) (bool, error) {
+		values = append(values, page...)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// VisitPaged hands the values of every page of a paginated list endpoint to visit in order, it stops
+// following the next links as soon as visit returns false, e.g. once a sorted list had what was needed
+func (c *Client) VisitPaged(endpoint string, visit func(values []json.RawMessage) (bool, error)) error {
+	seen := make(map[string]bool)
+
+	// The next links keep the pagelen of the first page
+	if c.PageSize > 0 {
+		separator := "?"
+		if strings.Contains(endpoint, "?") {
+			separator = "&"
+		}
+		endpoint = fmt.Sprintf("%s%spagelen=%d", endpoint, separator, c.PageSize)
+	}
+
+	for pages := 0; endpoint != ""; pages++ {
+		endpoint = versionedEndpoint(endpoint)
+		if seen[endpoint] || pages == maxPages {
+			return fmt.Errorf("stopped following the pages of %s after %d, the next link never ran out", endpoint, pages)
+		}
+		seen[endpoint] = true
+
+		resp, err := c.Get(endpoint)
+		if err != nil {
+			return err
+		}
+
+		var page struct {
+			Values []json.RawMessage `json:"values"`
+			Next   string            `json:"next"`
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		more, err := visit(page.Values)
+		if err != nil || !more {
+			return err
+		}
+
+		// A proxy may hand out the next links of bitbucket itself
+		endpoint = strings.TrimPrefix(strings.TrimPrefix(page.Next, c.baseURL()), BitbucketEndpoint)
+	}
+
+	return nil
+}
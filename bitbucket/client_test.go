@@ -0,0 +1,467 @@
+package bitbucket
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClient_stats(t *testing.T) {
+	calls := 0
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/2.0/rate-limited" && calls <= 2 {
+			w.Header().Set("X-RateLimit-Limit", "1000")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer closeServer()
+
+	client.MaxRetries = 3
+
+	if _, err := client.Get("2.0/rate-limited"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := client.Get("2.0/fine"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := ClientStats{Requests: 4, Retries: 2, RateLimited: 2}
+	if stats := client.Stats(); stats != expected {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestClient_tokenAuth(t *testing.T) {
+	var authorization string
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`{}`))
+	}))
+	defer closeServer()
+
+	client.Token = "secret-token"
+
+	if _, err := client.Get("2.0/user"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if authorization != "Bearer secret-token" {
+		t.Fatalf("expected a bearer token, got %q", authorization)
+	}
+}
+
+func TestClient_oauthClientCredentials(t *testing.T) {
+	var exchanges int
+	var authorizations []string
+	expiresIn := 3600
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/site/oauth2/access_token" {
+			id, secret, _ := r.BasicAuth()
+			r.ParseForm()
+			if id != "client-id" || secret != "client-secret" || r.PostForm.Get("grant_type") != "client_credentials" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			exchanges++
+			fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": %d, "token_type": "bearer"}`, exchanges, expiresIn)
+			return
+		}
+
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		w.Write([]byte(`{}`))
+	}))
+	defer closeServer()
+
+	client.OAuthClientID = "client-id"
+	client.OAuthClientSecret = "client-secret"
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get("2.0/user"); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	if exchanges != 1 || authorizations[0] != "Bearer token-1" || authorizations[1] != "Bearer token-1" {
+		t.Fatalf("expected the token to be cached, got %d exchanges and %v", exchanges, authorizations)
+	}
+
+	// An expired token is exchanged again
+	client.oauthExpiry = time.Now()
+	if _, err := client.Get("2.0/user"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if exchanges != 2 || authorizations[2] != "Bearer token-2" {
+		t.Fatalf("expected the token to be refreshed, got %d exchanges and %v", exchanges, authorizations)
+	}
+
+	client.OAuthClientSecret = "wrong"
+	client.oauthExpiry = time.Now()
+	if _, err := client.Get("2.0/user"); err == nil || !strings.Contains(err.Error(), "Failed to get an OAuth access token") {
+		t.Fatalf("expected the failed exchange to be an error, got %v", err)
+	}
+}
+
+func TestClient_versionedEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"repositories/test-owner/test-repo":     "/2.0/repositories/test-owner/test-repo",
+		"2.0/repositories/test-owner/test-repo": "/2.0/repositories/test-owner/test-repo",
+		"1.0/groups/test-owner/developers":      "/1.0/groups/test-owner/developers",
+	}
+
+	for endpoint, expected := range cases {
+		t.Run(endpoint, func(t *testing.T) {
+			var path string
+
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				w.Write([]byte(`{}`))
+			}))
+			defer closeServer()
+
+			if _, err := client.Get(endpoint); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if path != expected {
+				t.Fatalf("expected a request to %s, got %s", expected, path)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	backoff := 4 * time.Second
+
+	cases := map[string]struct {
+		RetryAfter string
+		Expected   time.Duration
+	}{
+		"no header":   {Expected: backoff},
+		"seconds":     {RetryAfter: "2", Expected: 2 * time.Second},
+		"past date":   {RetryAfter: "Mon, 02 Jan 2006 15:04:05 GMT", Expected: 0},
+		"unparseable": {RetryAfter: "soon", Expected: backoff},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tc.RetryAfter != "" {
+				resp.Header.Set("Retry-After", tc.RetryAfter)
+			}
+
+			if delay := retryDelay(resp, backoff); delay != tc.Expected {
+				t.Fatalf("expected %s, got %s", tc.Expected, delay)
+			}
+		})
+	}
+}
+
+func TestClient_retriesExhausted(t *testing.T) {
+	calls := 0
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer closeServer()
+
+	client.MaxRetries = 2
+	client.RetryBaseDelay = time.Hour
+
+	d := resourceBranchRestriction().Data(nil)
+	d.SetId("1")
+	d.Set("owner", "test-owner")
+	d.Set("repository", "test-repo")
+
+	err := resourceBranchRestrictionsRead(d, client)
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("expected the read to fail once the retries ran out, got %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+	if d.Id() != "1" {
+		t.Fatal("expected a rate limited read to keep the resource in state")
+	}
+}
+
+func TestClient_getPaged(t *testing.T) {
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Write([]byte(`{"values": [1, 2], "next": "https://api.bitbucket.org/2.0/things?page=2"}`))
+		case "2":
+			w.Write([]byte(`{"values": [3], "next": "https://api.bitbucket.org/2.0/things?page=3"}`))
+		case "3":
+			w.Write([]byte(`{"values": [4]}`))
+		}
+	}))
+	defer closeServer()
+
+	values, err := client.GetPaged("things")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var got []string
+	for _, value := range values {
+		got = append(got, string(value))
+	}
+	if strings.Join(got, ",") != "1,2,3,4" {
+		t.Fatalf("expected the values of every page, got %v", got)
+	}
+}
+
+func TestClient_getPagedPageSize(t *testing.T) {
+	var queries []string
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Query().Get("page") == "" {
+			w.Write([]byte(`{"values": [1], "next": "https://api.bitbucket.org/2.0/things?kind=push&pagelen=25&page=2"}`))
+			return
+		}
+		w.Write([]byte(`{"values": [2]}`))
+	}))
+	defer closeServer()
+
+	client.PageSize = 25
+
+	for _, endpoint := range []string{"things", "things?kind=push"} {
+		queries = nil
+
+		if _, err := client.GetPaged(endpoint); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if len(queries) != 2 || !strings.HasSuffix(queries[0], "pagelen=25") || queries[1] != "kind=push&pagelen=25&page=2" {
+			t.Fatalf("expected pagelen to be asked for once and kept by the next link, got %v", queries)
+		}
+	}
+}
+
+func TestClient_getPagedStopsOnRepeatedNext(t *testing.T) {
+	calls := 0
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"values": [1], "next": "https://api.bitbucket.org/2.0/things?page=2"}`))
+	}))
+	defer closeServer()
+
+	if _, err := client.GetPaged("things"); err == nil {
+		t.Fatal("expected an error when the next link keeps pointing at the same page")
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 requests, got %d", calls)
+	}
+}
+
+func TestClient_baseURL(t *testing.T) {
+	var paths []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.RequestURI())
+		if r.URL.Query().Get("page") == "" {
+			// Passed through from bitbucket by the proxy
+			w.Write([]byte(`{"values": [1], "next": "https://api.bitbucket.org/2.0/repositories/test-owner?page=2"}`))
+			return
+		}
+		w.Write([]byte(`{"values": [2]}`))
+	}))
+	defer server.Close()
+
+	client := &Client{
+		BaseURL:    server.URL + "/bitbucket/",
+		HTTPClient: &http.Client{},
+	}
+
+	values, err := client.GetPaged("repositories/test-owner")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(values) != 2 {
+		t.Fatalf("expected the values of both pages, got %d", len(values))
+	}
+
+	expected := []string{"/bitbucket/2.0/repositories/test-owner", "/bitbucket/2.0/repositories/test-owner?page=2"}
+	if strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected requests to %v, got %v", expected, paths)
+	}
+}
+
+func TestClient_dryRun(t *testing.T) {
+	var methods []string
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.Write([]byte(`{"name": "test-repo"}`))
+	}))
+	defer closeServer()
+
+	client.DryRun = true
+
+	requests := map[string]func() (*http.Response, error){
+		"POST": func() (*http.Response, error) {
+			return client.Post("repositories/test-owner/test-repo", bytes.NewBufferString(`{}`))
+		},
+		"PUT": func() (*http.Response, error) {
+			return client.Put("repositories/test-owner/test-repo", bytes.NewBufferString(`{}`))
+		},
+		"DELETE": func() (*http.Response, error) {
+			return client.Delete("repositories/test-owner/test-repo")
+		},
+		"form": func() (*http.Response, error) {
+			return client.PostForm("repositories/test-owner/test-repo/src", url.Values{})
+		},
+	}
+
+	for name, request := range requests {
+		resp, err := request()
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected a successful response, got %d", name, resp.StatusCode)
+		}
+	}
+
+	if len(methods) != 0 {
+		t.Fatalf("expected nothing to be sent, got %v", methods)
+	}
+
+	resp, err := client.Get("repositories/test-owner/test-repo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if len(methods) != 1 || string(body) != `{"name": "test-repo"}` {
+		t.Fatalf("expected reads to still be sent, got %v with %s", methods, body)
+	}
+}
+
+func TestClient_timeout(t *testing.T) {
+	// Hangs until the client gives up
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer closeServer()
+
+	client.HTTPClient.Timeout = 10 * time.Millisecond
+
+	_, err := client.Get("repositories/test-owner/test-repo")
+	if err == nil {
+		t.Fatal("expected the request to time out")
+	}
+	if !strings.Contains(err.Error(), "GET 2.0/repositories/test-owner/test-repo timed out") {
+		t.Fatalf("expected the error to name the endpoint, got %s", err)
+	}
+	if !isTimeout(err) {
+		t.Fatalf("expected the timeout to be wrapped, got %#v", err)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	cases := map[string]struct {
+		Body     string
+		Expected string
+	}{
+		"error envelope": {
+			Body:     `{"type": "error", "error": {"message": "Repository name is invalid"}}`,
+			Expected: "API Error: 400 POST 2.0/repositories/test-owner/test-repo?fields=name Repository name is invalid",
+		},
+		"plain body": {
+			Body:     `Bad Request`,
+			Expected: "API Error: 400 POST 2.0/repositories/test-owner/test-repo?fields=name Bad Request",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := parseError(&http.Response{
+				StatusCode: http.StatusBadRequest,
+				Body:       ioutil.NopCloser(strings.NewReader(tc.Body)),
+				Request:    httptest.NewRequest("POST", "https://api.bitbucket.org/2.0/repositories/test-owner/test-repo?fields=name", nil),
+			})
+
+			apiErr, ok := err.(Error)
+			if !ok {
+				t.Fatalf("expected an Error, got %#v", err)
+			}
+			if apiErr.StatusCode != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", apiErr.StatusCode)
+			}
+			if err.Error() != tc.Expected {
+				t.Fatalf("expected %q, got %q", tc.Expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestClient_oauthTokenRejected(t *testing.T) {
+	var mutex sync.Mutex
+	var exchanges int
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if r.URL.Path == "/site/oauth2/access_token" {
+			exchanges++
+			fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 3600, "token_type": "bearer"}`, exchanges+1)
+			return
+		}
+
+		// token-1 was revoked before it expired
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"type": "error", "error": {"message": "Access token expired"}}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer closeServer()
+
+	client.OAuthClientID = "client-id"
+	client.OAuthClientSecret = "client-secret"
+	client.oauthToken = "token-1"
+	client.oauthExpiry = time.Now().Add(time.Hour)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Get("2.0/user")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("expected the request to be sent again with a new token, got %s", err)
+		}
+	}
+	if exchanges != 1 {
+		t.Fatalf("expected the rejected token to be exchanged once, got %d exchanges", exchanges)
+	}
+}
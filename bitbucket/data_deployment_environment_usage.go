@@ -0,0 +1,241 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// Environment is a deployment environment of a repository
+type Environment struct {
+	UUID            string          `json:"uuid,omitempty"`
+	Name            string          `json:"name,omitempty"`
+	EnvironmentType EnvironmentType `json:"environment_type,omitempty"`
+}
+
+// EnvironmentType is the kind of environment, Test, Staging or Production, the rank orders them
+type EnvironmentType struct {
+	Name string `json:"name,omitempty"`
+	Rank int    `json:"rank"`
+	Type string `json:"type,omitempty"`
+}
+
+// PaginatedEnvironments is a paginated list of environments that the bitbucket api returns
+type PaginatedEnvironments struct {
+	Values []Environment `json:"values,omitempty"`
+	Page   int           `json:"page,omitempty"`
+	Size   int           `json:"size,omitempty"`
+	Next   string        `json:"next,omitempty"`
+}
+
+// Deployment is a pipeline deploying a release to an environment
+type Deployment struct {
+	UUID        string            `json:"uuid,omitempty"`
+	Environment Environment       `json:"environment,omitempty"`
+	State       DeploymentState   `json:"state,omitempty"`
+	Release     DeploymentRelease `json:"release,omitempty"`
+}
+
+// DeploymentState is where a deployment is at
+type DeploymentState struct {
+	Name        string `json:"name,omitempty"`
+	StartedOn   string `json:"started_on,omitempty"`
+	CompletedOn string `json:"completed_on,omitempty"`
+}
+
+// DeploymentRelease is the commit a deployment released
+type DeploymentRelease struct {
+	Name   string `json:"name,omitempty"`
+	Commit Target `json:"commit,omitempty"`
+}
+
+// PaginatedDeployments is a paginated list of deployments that the bitbucket api returns
+type PaginatedDeployments struct {
+	Values []Deployment `json:"values,omitempty"`
+	Page   int          `json:"page,omitempty"`
+	Size   int          `json:"size,omitempty"`
+	Next   string       `json:"next,omitempty"`
+}
+
+func dataSourceDeploymentEnvironmentUsage() *schema.Resource {
+	return &schema.Resource{
+		Read: dataReadDeploymentEnvironmentUsage,
+
+		Schema: map[string]*schema.Schema{
+			"owner": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"repository": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"environments": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"uuid": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"environment_type": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"used": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"last_deployment_state": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"last_deployed_on": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"last_release": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"last_commit": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// environmentTypeRanks orders environments the way bitbucket shows them, from test to production
+var environmentTypeRanks = map[string]int{
+	"Test":       0,
+	"Staging":    1,
+	"Production": 2,
+}
+
+// sortEnvironments orders environments by type, name and uuid so the list doesn't depend on the
+// order the api happened to return them in
+func sortEnvironments(environments []Environment) {
+	sort.Slice(environments, func(i, j int) bool {
+		a, b := environments[i], environments[j]
+		if rankA, rankB := environmentTypeRanks[a.EnvironmentType.Name], environmentTypeRanks[b.EnvironmentType.Name]; rankA != rankB {
+			return rankA < rankB
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.UUID < b.UUID
+	})
+}
+
+func getEnvironments(c *Client, owner, repoSlug string) ([]Environment, error) {
+	values, err := c.GetPaged(fmt.Sprintf("repositories/%s/%s/environments/", owner, repoSlug))
+	if err != nil {
+		return nil, err
+	}
+
+	environments := make([]Environment, 0, len(values))
+	for _, value := range values {
+		var environment Environment
+		if err := json.Unmarshal(value, &environment); err != nil {
+			return nil, err
+		}
+		environments = append(environments, environment)
+	}
+
+	return environments, nil
+}
+
+// maxRecentDeployments bounds how far back getLastDeployments looks for an environment nothing was
+// deployed to lately, the newest deployments come first so that is all a busy repository needs
+const maxRecentDeployments = 1000
+
+// getLastDeployments returns the most recent deployment of every environment that was deployed to
+// within the last maxRecentDeployments deployments, it stops once every environment has one
+func getLastDeployments(c *Client, owner, repoSlug string, environments []Environment) (map[string]Deployment, error) {
+	lastDeployments := make(map[string]Deployment)
+	seen := 0
+
+	err := c.VisitPaged(fmt.Sprintf("repositories/%s/%s/deployments/?sort=-state.started_on", owner, repoSlug),
+		func(values []json.RawMessage) (bool, error) {
+			for _, value := range values {
+				var deployment Deployment
+				if err := json.Unmarshal(value, &deployment); err != nil {
+					return false, err
+				}
+
+				last, ok := lastDeployments[deployment.Environment.UUID]
+				if !ok || formatTimestamp(deployment.State.StartedOn) > formatTimestamp(last.State.StartedOn) {
+					lastDeployments[deployment.Environment.UUID] = deployment
+				}
+			}
+
+			seen += len(values)
+			if seen >= maxRecentDeployments {
+				return false, nil
+			}
+
+			for _, environment := range environments {
+				if _, ok := lastDeployments[environment.UUID]; !ok {
+					return true, nil
+				}
+			}
+			return false, nil
+		})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lastDeployments, nil
+}
+
+func dataReadDeploymentEnvironmentUsage(d *schema.ResourceData, m interface{}) error {
+	c := m.(*Client)
+
+	owner := d.Get("owner").(string)
+	repoSlug := d.Get("repository").(string)
+
+	environments, err := getEnvironments(c, owner, repoSlug)
+	if err != nil {
+		return err
+	}
+	sortEnvironments(environments)
+
+	lastDeployments, err := getLastDeployments(c, owner, repoSlug, environments)
+	if err != nil {
+		return err
+	}
+
+	usage := make([]map[string]interface{}, 0, len(environments))
+
+	for _, environment := range environments {
+		deployment, used := lastDeployments[environment.UUID]
+
+		usage = append(usage, map[string]interface{}{
+			"uuid":                  environment.UUID,
+			"name":                  environment.Name,
+			"environment_type":      environment.EnvironmentType.Name,
+			"used":                  used,
+			"last_deployment_state": deployment.State.Name,
+			"last_deployed_on":      formatTimestamp(deployment.State.StartedOn),
+			"last_release":          deployment.Release.Name,
+			"last_commit":           deployment.Release.Commit.Hash,
+		})
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", owner, repoSlug))
+	d.Set("environments", usage)
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package bitbucket
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func TestDeploymentEnvironmentUsageRead(t *testing.T) {
+	pages := map[string]string{
+		"/2.0/repositories/test-owner/test-repo/environments/": `{"page": 1, "next": "https://api.bitbucket.org/2.0/repositories/test-owner/test-repo/environments/?page=2", "values": [
+			{"uuid": "{test}", "name": "Test", "environment_type": {"name": "Test"}}
+		]}`,
+		"/2.0/repositories/test-owner/test-repo/environments/?page=2": `{"page": 2, "values": [
+			{"uuid": "{production}", "name": "Production", "environment_type": {"name": "Production"}}
+		]}`,
+		// Production was never deployed to, so every page is read
+		"/2.0/repositories/test-owner/test-repo/deployments/?sort=-state.started_on": `{"page": 1, "next": "https://api.bitbucket.org/2.0/repositories/test-owner/test-repo/deployments/?sort=-state.started_on&page=2", "values": [
+			{"uuid": "{d2}", "environment": {"uuid": "{test}"}, "state": {"name": "IN_PROGRESS", "started_on": "2020-02-01T10:00:00.000000+00:00"},
+			 "release": {"name": "#2", "commit": {"hash": "bbbbbbb"}}}
+		]}`,
+		"/2.0/repositories/test-owner/test-repo/deployments/?sort=-state.started_on&page=2": `{"page": 2, "values": [
+			{"uuid": "{d1}", "environment": {"uuid": "{test}"}, "state": {"name": "COMPLETED", "started_on": "2020-01-01T10:00:00.000000+00:00"},
+			 "release": {"name": "#1", "commit": {"hash": "aaaaaaa"}}}
+		]}`,
+	}
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.RequestURI()]
+		if !ok {
+			t.Errorf("unexpected request to %s", r.URL.RequestURI())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer closeServer()
+
+	d := schema.TestResourceDataRaw(t, dataSourceDeploymentEnvironmentUsage().Schema, map[string]interface{}{
+		"owner":      "test-owner",
+		"repository": "test-repo",
+	})
+
+	if err := dataReadDeploymentEnvironmentUsage(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	environments := d.Get("environments").([]interface{})
+	if len(environments) != 2 {
+		t.Fatalf("expected 2 environments, got %d", len(environments))
+	}
+
+	test := environments[0].(map[string]interface{})
+	if test["name"] != "Test" || test["used"] != true {
+		t.Fatalf("expected Test to be used, got %v", test)
+	}
+	if test["last_deployment_state"] != "IN_PROGRESS" || test["last_commit"] != "bbbbbbb" ||
+		test["last_deployed_on"] != "2020-02-01T10:00:00Z" {
+		t.Fatalf("expected the most recent deployment of Test, got %v", test)
+	}
+
+	production := environments[1].(map[string]interface{})
+	if production["name"] != "Production" || production["used"] != false || production["last_deployed_on"] != "" {
+		t.Fatalf("expected Production to be unused, got %v", production)
+	}
+}
+
+func TestGetLastDeployments_stopsOnceEveryEnvironmentHasOne(t *testing.T) {
+	var requested []string
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.RequestURI())
+		w.Write([]byte(`{"next": "https://api.bitbucket.org/2.0/repositories/test-owner/test-repo/deployments/?sort=-state.started_on&page=2", "values": [
+			{"uuid": "{d2}", "environment": {"uuid": "{production}"}, "state": {"started_on": "2020-02-01T10:00:00.000000+00:00"}},
+			{"uuid": "{d1}", "environment": {"uuid": "{test}"}, "state": {"started_on": "2020-01-01T10:00:00.000000+00:00"}}
+		]}`))
+	}))
+	defer closeServer()
+
+	deployments, err := getLastDeployments(client, "test-owner", "test-repo", []Environment{{UUID: "{test}"}, {UUID: "{production}"}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(requested) != 1 || deployments["{test}"].UUID != "{d1}" || deployments["{production}"].UUID != "{d2}" {
+		t.Fatalf("expected the first page to be enough, got %v after requesting %v", deployments, requested)
+	}
+}
+
+func TestDeploymentEnvironmentUsageRead_orderIsStable(t *testing.T) {
+	read := func(environments string) []interface{} {
+		client, closeServer := testClient(t, testResponses(map[string]string{
+			"/2.0/repositories/test-owner/test-repo/environments/": environments,
+			"/2.0/repositories/test-owner/test-repo/deployments/":  `{"values": []}`,
+		}))
+		defer closeServer()
+
+		d := schema.TestResourceDataRaw(t, dataSourceDeploymentEnvironmentUsage().Schema, map[string]interface{}{
+			"owner":      "test-owner",
+			"repository": "test-repo",
+		})
+
+		if err := dataReadDeploymentEnvironmentUsage(d, client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		return d.Get("environments").([]interface{})
+	}
+
+	environments := read(`{"values": [
+		{"uuid": "{production}", "name": "Production", "environment_type": {"name": "Production"}},
+		{"uuid": "{staging}", "name": "Staging", "environment_type": {"name": "Staging"}},
+		{"uuid": "{test}", "name": "Test", "environment_type": {"name": "Test"}}
+	]}`)
+	shuffled := read(`{"values": [
+		{"uuid": "{staging}", "name": "Staging", "environment_type": {"name": "Staging"}},
+		{"uuid": "{test}", "name": "Test", "environment_type": {"name": "Test"}},
+		{"uuid": "{production}", "name": "Production", "environment_type": {"name": "Production"}}
+	]}`)
+
+	if !reflect.DeepEqual(environments, shuffled) {
+		t.Fatalf("expected the same environments in the same order, got %v and %v", environments, shuffled)
+	}
+
+	var names []string
+	for _, environment := range environments {
+		names = append(names, environment.(map[string]interface{})["name"].(string))
+	}
+	if !reflect.DeepEqual(names, []string{"Test", "Staging", "Production"}) {
+		t.Fatalf("expected environments from test to production, got %v", names)
+	}
+}
@@ -0,0 +1,94 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// WorkspaceGroup is a group as the 1.0 groups api returns it
+type WorkspaceGroup struct {
+	Name       string `json:"name"`
+	Slug       string `json:"slug"`
+	Permission string `json:"permission"`
+	AutoAdd    bool   `json:"auto_add"`
+}
+
+func dataGroup() *schema.Resource {
+	return &schema.Resource{
+		Read: dataReadGroup,
+
+		Schema: map[string]*schema.Schema{
+			"workspace": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"slug": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"permission": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"auto_add": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataReadGroup(d *schema.ResourceData, m interface{}) error {
+	c := m.(*Client)
+
+	workspace := d.Get("workspace").(string)
+	name := d.Get("name").(string)
+
+	// The 1.0 api can't look a group up by name, only list all of them
+	groupsReq, err := c.Get(fmt.Sprintf("1.0/groups/%s", workspace))
+	if err != nil {
+		return err
+	}
+
+	var groups []WorkspaceGroup
+
+	decodeerr := json.NewDecoder(groupsReq.Body).Decode(&groups)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	var matches []WorkspaceGroup
+	for _, group := range groups {
+		if group.Name == name {
+			matches = append(matches, group)
+		}
+	}
+
+	if len(matches) == 0 {
+		return fmt.Errorf("group %s not found in workspace %s", name, workspace)
+	}
+	if len(matches) > 1 {
+		slugs := make([]string, 0, len(matches))
+		for _, group := range matches {
+			slugs = append(slugs, group.Slug)
+		}
+		return fmt.Errorf("group name %s is ambiguous in workspace %s, it matches the groups %s",
+			name, workspace, strings.Join(slugs, ", "))
+	}
+
+	group := matches[0]
+
+	d.SetId(fmt.Sprintf("%s/%s", workspace, group.Slug))
+	d.Set("slug", group.Slug)
+	d.Set("permission", group.Permission)
+	d.Set("auto_add", group.AutoAdd)
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package bitbucket
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataGroupMembers() *schema.Resource {
+	return &schema.Resource{
+		Read: dataReadGroupMembers,
+
+		Schema: map[string]*schema.Schema{
+			"owner": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"slug": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"members": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"uuid": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"display_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataReadGroupMembers(d *schema.ResourceData, m interface{}) error {
+	c := m.(*Client)
+
+	owner := d.Get("owner").(string)
+	slug := d.Get("slug").(string)
+
+	groupMembers, err := listGroupMembers(c, owner, slug)
+	if err != nil {
+		return err
+	}
+
+	// Sorted so the list doesn't depend on the order the api returned the members in
+	sort.Slice(groupMembers, func(i, j int) bool {
+		if groupMembers[i].DisplayName != groupMembers[j].DisplayName {
+			return groupMembers[i].DisplayName < groupMembers[j].DisplayName
+		}
+		return groupMembers[i].UUID < groupMembers[j].UUID
+	})
+
+	members := make([]map[string]interface{}, 0, len(groupMembers))
+	for _, member := range groupMembers {
+		members = append(members, map[string]interface{}{
+			"uuid":         member.UUID,
+			"display_name": member.DisplayName,
+		})
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", owner, slug))
+	d.Set("members", members)
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package bitbucket
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func TestGroupMembersRead(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(map[string]string{
+		"/1.0/groups/test-owner/platform/members": `[
+			{"uuid": "{alice}", "display_name": "Alice"},
+			{"uuid": "{bob}", "display_name": "Bob"}
+		]`,
+	}))
+	defer closeServer()
+
+	d := schema.TestResourceDataRaw(t, dataGroupMembers().Schema, map[string]interface{}{
+		"owner": "test-owner",
+		"slug":  "platform",
+	})
+
+	if err := dataReadGroupMembers(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if d.Id() != "test-owner/platform" {
+		t.Fatalf("expected ID test-owner/platform, got %s", d.Id())
+	}
+
+	members := d.Get("members").([]interface{})
+	if len(members) != 2 {
+		t.Fatalf("expected 2 members, got %d", len(members))
+	}
+	if uuid := members[1].(map[string]interface{})["uuid"]; uuid != "{bob}" {
+		t.Fatalf("expected {bob}, got %v", uuid)
+	}
+}
+
+func TestGroupMembersRead_orderIsStable(t *testing.T) {
+	read := func(body string) []interface{} {
+		client, closeServer := testClient(t, testResponses(map[string]string{
+			"/1.0/groups/test-owner/platform/members": body,
+		}))
+		defer closeServer()
+
+		d := schema.TestResourceDataRaw(t, dataGroupMembers().Schema, map[string]interface{}{
+			"owner": "test-owner",
+			"slug":  "platform",
+		})
+
+		if err := dataReadGroupMembers(d, client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		return d.Get("members").([]interface{})
+	}
+
+	members := read(`[{"uuid": "{bob}", "display_name": "Bob"}, {"uuid": "{alice-b}", "display_name": "Alice"}, {"uuid": "{alice-a}", "display_name": "Alice"}]`)
+	shuffled := read(`[{"uuid": "{alice-a}", "display_name": "Alice"}, {"uuid": "{bob}", "display_name": "Bob"}, {"uuid": "{alice-b}", "display_name": "Alice"}]`)
+
+	if !reflect.DeepEqual(members, shuffled) {
+		t.Fatalf("expected the same members in the same order, got %v and %v", members, shuffled)
+	}
+	if uuid := members[0].(map[string]interface{})["uuid"]; uuid != "{alice-a}" {
+		t.Fatalf("expected {alice-a} first, got %v", uuid)
+	}
+}
@@ -0,0 +1,45 @@
+package bitbucket
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func TestGroupRead(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(map[string]string{
+		"/1.0/groups/test-owner": `[
+			{"name": "Administrators", "slug": "administrators", "permission": "admin", "auto_add": false},
+			{"name": "Platform Team", "slug": "platform-team", "permission": "write", "auto_add": true},
+			{"name": "Shared", "slug": "shared-1", "permission": "read"},
+			{"name": "Shared", "slug": "shared-2", "permission": "read"}
+		]`,
+	}))
+	defer closeServer()
+
+	read := func(name string) (*schema.ResourceData, error) {
+		d := schema.TestResourceDataRaw(t, dataGroup().Schema, map[string]interface{}{
+			"workspace": "test-owner",
+			"name":      name,
+		})
+		return d, dataReadGroup(d, client)
+	}
+
+	d, err := read("Platform Team")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.Id() != "test-owner/platform-team" || d.Get("slug").(string) != "platform-team" ||
+		d.Get("permission").(string) != "write" || !d.Get("auto_add").(bool) {
+		t.Fatalf("unexpected group %s %#v", d.Id(), d.State().Attributes)
+	}
+
+	if _, err := read("Missing"); err == nil || err.Error() != "group Missing not found in workspace test-owner" {
+		t.Fatalf("expected the group not to be found, got %v", err)
+	}
+
+	_, err = read("Shared")
+	if err == nil || err.Error() != "group name Shared is ambiguous in workspace test-owner, it matches the groups shared-1, shared-2" {
+		t.Fatalf("expected the group name to be ambiguous, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// UserPermission is the permission a user was given on a repository
+type UserPermission struct {
+	Permission string `json:"permission,omitempty"`
+	User       struct {
+		UUID        string `json:"uuid,omitempty"`
+		DisplayName string `json:"display_name,omitempty"`
+	} `json:"user,omitempty"`
+}
+
+// PaginatedUserPermissions is a paginated list of user permissions that the bitbucket api returns
+type PaginatedUserPermissions struct {
+	Values []UserPermission `json:"values,omitempty"`
+	Page   int              `json:"page,omitempty"`
+	Size   int              `json:"size,omitempty"`
+	Next   string           `json:"next,omitempty"`
+}
+
+// GroupPermission is the permission a group was given on a repository
+type GroupPermission struct {
+	Permission string `json:"permission,omitempty"`
+	Group      struct {
+		Slug string `json:"slug,omitempty"`
+		Name string `json:"name,omitempty"`
+	} `json:"group,omitempty"`
+}
+
+// PaginatedGroupPermissions is a paginated list of group permissions that the bitbucket api returns
+type PaginatedGroupPermissions struct {
+	Values []GroupPermission `json:"values,omitempty"`
+	Page   int               `json:"page,omitempty"`
+	Size   int               `json:"size,omitempty"`
+	Next   string            `json:"next,omitempty"`
+}
+
+// permissionLevels maps the permissions bitbucket reports to the attribute listing who has them
+var permissionLevels = map[string]string{
+	"admin": "admins",
+	"write": "writers",
+	"read":  "readers",
+}
+
+func permissionGranteesSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"type": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"id": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"name": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+			},
+		},
+	}
+}
+
+// sortGrantees orders users before groups and each by name and id so the lists don't depend on the
+// order the api returned the permissions in
+func sortGrantees(grantees []map[string]interface{}) {
+	sort.Slice(grantees, func(i, j int) bool {
+		a, b := grantees[i], grantees[j]
+		if a["type"] != b["type"] {
+			return a["type"] == "user"
+		}
+		if a["name"] != b["name"] {
+			return a["name"].(string) < b["name"].(string)
+		}
+		return a["id"].(string) < b["id"].(string)
+	})
+}
+
+func dataSourceRepositoryPermissionsSummary() *schema.Resource {
+	return &schema.Resource{
+		Read: dataReadRepositoryPermissionsSummary,
+
+		Schema: map[string]*schema.Schema{
+			"owner": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"repository": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"admins":  permissionGranteesSchema(),
+			"writers": permissionGranteesSchema(),
+			"readers": permissionGranteesSchema(),
+			"admin_count": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"writer_count": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"reader_count": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func getUserPermissions(c *Client, owner, repoSlug string) ([]UserPermission, error) {
+	values, err := c.GetPaged(fmt.Sprintf("repositories/%s/%s/permissions-config/users", owner, repoSlug))
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]UserPermission, 0, len(values))
+	for _, value := range values {
+		var permission UserPermission
+		if err := json.Unmarshal(value, &permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, nil
+}
+
+func getGroupPermissions(c *Client, owner, repoSlug string) ([]GroupPermission, error) {
+	values, err := c.GetPaged(fmt.Sprintf("repositories/%s/%s/permissions-config/groups", owner, repoSlug))
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]GroupPermission, 0, len(values))
+	for _, value := range values {
+		var permission GroupPermission
+		if err := json.Unmarshal(value, &permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, nil
+}
+
+func dataReadRepositoryPermissionsSummary(d *schema.ResourceData, m interface{}) error {
+	c := m.(*Client)
+
+	owner := d.Get("owner").(string)
+	repoSlug := d.Get("repository").(string)
+
+	userPermissions, err := getUserPermissions(c, owner, repoSlug)
+	if err != nil {
+		return err
+	}
+
+	groupPermissions, err := getGroupPermissions(c, owner, repoSlug)
+	if err != nil {
+		return err
+	}
+
+	grantees := make(map[string][]map[string]interface{})
+	for _, attribute := range permissionLevels {
+		grantees[attribute] = []map[string]interface{}{}
+	}
+
+	for _, permission := range userPermissions {
+		if attribute, ok := permissionLevels[permission.Permission]; ok {
+			grantees[attribute] = append(grantees[attribute], map[string]interface{}{
+				"type": "user",
+				"id":   permission.User.UUID,
+				"name": permission.User.DisplayName,
+			})
+		}
+	}
+
+	for _, permission := range groupPermissions {
+		if attribute, ok := permissionLevels[permission.Permission]; ok {
+			grantees[attribute] = append(grantees[attribute], map[string]interface{}{
+				"type": "group",
+				"id":   permission.Group.Slug,
+				"name": permission.Group.Name,
+			})
+		}
+	}
+
+	for _, attribute := range permissionLevels {
+		sortGrantees(grantees[attribute])
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", owner, repoSlug))
+	d.Set("admins", grantees["admins"])
+	d.Set("writers", grantees["writers"])
+	d.Set("readers", grantees["readers"])
+	d.Set("admin_count", len(grantees["admins"]))
+	d.Set("writer_count", len(grantees["writers"]))
+	d.Set("reader_count", len(grantees["readers"]))
+
+	return nil
+}
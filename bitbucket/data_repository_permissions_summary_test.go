@@ -0,0 +1,115 @@
+package bitbucket
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func TestRepositoryPermissionsSummaryRead(t *testing.T) {
+	pages := map[string]string{
+		"/2.0/repositories/test-owner/test-repo/permissions-config/users": `{"page": 1, "next": "https://api.bitbucket.org/2.0/repositories/test-owner/test-repo/permissions-config/users?page=2", "values": [
+			{"permission": "admin", "user": {"uuid": "{alice}", "display_name": "Alice"}},
+			{"permission": "write", "user": {"uuid": "{bob}", "display_name": "Bob"}}
+		]}`,
+		"/2.0/repositories/test-owner/test-repo/permissions-config/users?page=2": `{"page": 2, "values": [
+			{"permission": "read", "user": {"uuid": "{carol}", "display_name": "Carol"}}
+		]}`,
+		"/2.0/repositories/test-owner/test-repo/permissions-config/groups": `{"page": 1, "next": "https://api.bitbucket.org/2.0/repositories/test-owner/test-repo/permissions-config/groups?page=2", "values": [
+			{"permission": "write", "group": {"slug": "developers", "name": "Developers"}}
+		]}`,
+		"/2.0/repositories/test-owner/test-repo/permissions-config/groups?page=2": `{"page": 2, "values": [
+			{"permission": "admin", "group": {"slug": "administrators", "name": "Administrators"}}
+		]}`,
+	}
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.RequestURI()]
+		if !ok {
+			t.Errorf("unexpected request to %s", r.URL.RequestURI())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer closeServer()
+
+	d := schema.TestResourceDataRaw(t, dataSourceRepositoryPermissionsSummary().Schema, map[string]interface{}{
+		"owner":      "test-owner",
+		"repository": "test-repo",
+	})
+
+	if err := dataReadRepositoryPermissionsSummary(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for attribute, count := range map[string]int{"admin_count": 2, "writer_count": 2, "reader_count": 1} {
+		if v := d.Get(attribute).(int); v != count {
+			t.Fatalf("expected %s to be %d, got %d", attribute, count, v)
+		}
+	}
+
+	admins := d.Get("admins").([]interface{})
+	if admin := admins[1].(map[string]interface{}); admin["type"] != "group" || admin["id"] != "administrators" {
+		t.Fatalf("expected the administrators group from the second page, got %v", admin)
+	}
+
+	readers := d.Get("readers").([]interface{})
+	if reader := readers[0].(map[string]interface{}); reader["type"] != "user" || reader["id"] != "{carol}" || reader["name"] != "Carol" {
+		t.Fatalf("expected Carol to be a reader, got %v", reader)
+	}
+}
+
+func TestRepositoryPermissionsSummaryRead_orderIsStable(t *testing.T) {
+	read := func(users, groups string) map[string]interface{} {
+		client, closeServer := testClient(t, testResponses(map[string]string{
+			"/2.0/repositories/test-owner/test-repo/permissions-config/users":  users,
+			"/2.0/repositories/test-owner/test-repo/permissions-config/groups": groups,
+		}))
+		defer closeServer()
+
+		d := schema.TestResourceDataRaw(t, dataSourceRepositoryPermissionsSummary().Schema, map[string]interface{}{
+			"owner":      "test-owner",
+			"repository": "test-repo",
+		})
+
+		if err := dataReadRepositoryPermissionsSummary(d, client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		return map[string]interface{}{
+			"admins":  d.Get("admins"),
+			"writers": d.Get("writers"),
+			"readers": d.Get("readers"),
+		}
+	}
+
+	summary := read(`{"values": [
+		{"permission": "write", "user": {"uuid": "{bob}", "display_name": "Bob"}},
+		{"permission": "write", "user": {"uuid": "{alice}", "display_name": "Alice"}}
+	]}`, `{"values": [
+		{"permission": "write", "group": {"slug": "qa", "name": "QA"}},
+		{"permission": "write", "group": {"slug": "developers", "name": "Developers"}}
+	]}`)
+	shuffled := read(`{"values": [
+		{"permission": "write", "user": {"uuid": "{alice}", "display_name": "Alice"}},
+		{"permission": "write", "user": {"uuid": "{bob}", "display_name": "Bob"}}
+	]}`, `{"values": [
+		{"permission": "write", "group": {"slug": "developers", "name": "Developers"}},
+		{"permission": "write", "group": {"slug": "qa", "name": "QA"}}
+	]}`)
+
+	if !reflect.DeepEqual(summary, shuffled) {
+		t.Fatalf("expected the same grantees in the same order, got %v and %v", summary, shuffled)
+	}
+
+	var ids []string
+	for _, writer := range summary["writers"].([]interface{}) {
+		ids = append(ids, writer.(map[string]interface{})["id"].(string))
+	}
+	if !reflect.DeepEqual(ids, []string{"{alice}", "{bob}", "developers", "qa"}) {
+		t.Fatalf("expected users then groups by name, got %v", ids)
+	}
+}
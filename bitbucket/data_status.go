@@ -0,0 +1,68 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceStatus() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceStatusRead,
+
+		Schema: map[string]*schema.Schema{
+			"authenticated": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"account_uuid": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"latency_ms": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+		},
+	}
+}
+
+// dataSourceStatusRead asks for the current user, the cheapest request that needs the credentials.
+// Rejected credentials are reported rather than failing so configs can branch on them, but an
+// unreachable bitbucket still fails the plan.
+func dataSourceStatusRead(d *schema.ResourceData, m interface{}) error {
+	c := m.(*Client)
+
+	started := time.Now()
+	userReq, err := c.Get("user")
+	latency := time.Since(started)
+
+	d.SetId(c.baseURL())
+	d.Set("latency_ms", int(latency/time.Millisecond))
+
+	if userReq != nil && (userReq.StatusCode == http.StatusUnauthorized || userReq.StatusCode == http.StatusForbidden) {
+		log.Printf("[WARN] Bitbucket rejected the credentials with %d", userReq.StatusCode)
+		d.Set("authenticated", false)
+		d.Set("account_uuid", "")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var u apiUser
+
+	decodeerr := json.NewDecoder(userReq.Body).Decode(&u)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	d.Set("authenticated", true)
+	d.Set("account_uuid", u.UUID)
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package bitbucket
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func TestStatusRead(t *testing.T) {
+	cases := map[string]struct {
+		StatusCode    int
+		Authenticated bool
+		AccountUUID   string
+		Error         bool
+	}{
+		"authenticated": {
+			StatusCode:    http.StatusOK,
+			Authenticated: true,
+			AccountUUID:   "{gob}",
+		},
+		"unauthenticated": {
+			StatusCode: http.StatusUnauthorized,
+		},
+		"forbidden": {
+			StatusCode: http.StatusForbidden,
+		},
+		"unavailable": {
+			StatusCode: http.StatusServiceUnavailable,
+			Error:      true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/2.0/user" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				w.WriteHeader(tc.StatusCode)
+				if tc.StatusCode == http.StatusOK {
+					w.Write([]byte(`{"uuid": "{gob}", "display_name": "Gob"}`))
+				} else {
+					w.Write([]byte(`{"type": "error", "error": {"message": "Access denied"}}`))
+				}
+			}))
+			defer closeServer()
+
+			d := schema.TestResourceDataRaw(t, dataSourceStatus().Schema, map[string]interface{}{})
+
+			err := dataSourceStatusRead(d, client)
+			if tc.Error {
+				if err == nil {
+					t.Fatal("expected an unreachable bitbucket to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if d.Id() == "" || d.Get("authenticated").(bool) != tc.Authenticated || d.Get("account_uuid").(string) != tc.AccountUUID {
+				t.Fatalf("unexpected status %#v", d.State().Attributes)
+			}
+		})
+	}
+}
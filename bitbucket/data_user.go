@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/hashicorp/terraform/helper/schema"
 )
@@ -14,14 +16,32 @@ type apiUser struct {
 	Nickname    string `json:"nickname"`
 }
 
+type workspaceMembership struct {
+	User apiUser `json:"user"`
+}
+
+type paginatedWorkspaceMemberships struct {
+	Values []workspaceMembership `json:"values"`
+}
+
 func dataUser() *schema.Resource {
 	return &schema.Resource{
 		Read: dataReadUser,
 
 		Schema: map[string]*schema.Schema{
 			"username": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"email"},
+			},
+			"email": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"username"},
+			},
+			"workspace": {
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
 			},
 			"display_name": {
 				Type:     schema.TypeString,
@@ -42,35 +62,91 @@ func dataUser() *schema.Resource {
 func dataReadUser(d *schema.ResourceData, m interface{}) error {
 	c := m.(*Client)
 
-	username := d.Get("username")
+	var u apiUser
+	var err error
+
+	if email := d.Get("email").(string); email != "" {
+		workspace := d.Get("workspace").(string)
+		if workspace == "" {
+			return fmt.Errorf("workspace must be set to look up a user by email")
+		}
+
+		u, err = resolveUserByEmail(c, workspace, email)
+		if err != nil {
+			return err
+		}
+	} else {
+		u, err = getUser(c, d.Get("username").(string))
+		if err != nil {
+			return err
+		}
+	}
+
+	d.SetId(u.UUID)
+	d.Set("uuid", u.UUID)
+	d.Set("nickname", u.Nickname)
+	d.Set("display_name", u.DisplayName)
+
+	return nil
+}
+
+func getUser(c *Client, username string) (apiUser, error) {
+	var u apiUser
+
 	if username == "" {
-		return fmt.Errorf("username must not be blank")
+		return u, fmt.Errorf("username or email must not be blank")
 	}
 
-	r, err := c.Get(fmt.Sprintf("2.0/users/%s", username))
-	if err != nil {
-		return err
+	r, err := c.Get(fmt.Sprintf("users/%s", username))
+	if r != nil && r.StatusCode == http.StatusNotFound {
+		return u, fmt.Errorf("user not found")
 	}
 
-	if r.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("user not found")
+	if r != nil && r.StatusCode >= http.StatusInternalServerError {
+		return u, fmt.Errorf("internal server error fetching user")
 	}
 
-	if r.StatusCode >= http.StatusInternalServerError {
-		return fmt.Errorf("internal server error fetching user")
+	if err != nil {
+		return u, err
 	}
 
+	err = json.NewDecoder(r.Body).Decode(&u)
+	return u, err
+}
+
+// bbqlStringEscaper escapes a value so it can be quoted in a bbql query
+var bbqlStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// resolveUserByEmail finds the workspace member with the given email, bitbucket only matches
+// emails the member allows to be looked up so privacy settings can hide a valid user
+func resolveUserByEmail(c *Client, workspace, email string) (apiUser, error) {
 	var u apiUser
 
-	err = json.NewDecoder(r.Body).Decode(&u)
+	query := url.QueryEscape(fmt.Sprintf(`user.email="%s"`, bbqlStringEscaper.Replace(email)))
+	r, err := c.Get(fmt.Sprintf("workspaces/%s/members?q=%s", workspace, query))
+
+	if r != nil && (r.StatusCode == http.StatusBadRequest || r.StatusCode == http.StatusForbidden) {
+		return u, fmt.Errorf("bitbucket refused to look up %s by email (%d), the user's privacy settings or your "+
+			"permissions on workspace %s may not allow it, use their username or uuid instead", email, r.StatusCode, workspace)
+	}
+
 	if err != nil {
-		return err
+		return u, err
 	}
 
-	d.SetId(u.UUID)
-	d.Set("uuid", u.UUID)
-	d.Set("nickname", u.Nickname)
-	d.Set("display_name", u.DisplayName)
+	var members paginatedWorkspaceMemberships
 
-	return nil
+	err = json.NewDecoder(r.Body).Decode(&members)
+	if err != nil {
+		return u, err
+	}
+
+	switch len(members.Values) {
+	case 0:
+		return u, fmt.Errorf("no member of workspace %s has the email %s, or their privacy settings hide it", workspace, email)
+	case 1:
+		return members.Values[0].User, nil
+	default:
+		return u, fmt.Errorf("more than one member of workspace %s matched the email %s", workspace, email)
+	}
 }
@@ -0,0 +1,85 @@
+package bitbucket
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestResolveUserByEmail(t *testing.T) {
+	cases := map[string]struct {
+		Status   int
+		Response string
+		UUID     string
+		Error    string
+	}{
+		"found": {
+			Status:   http.StatusOK,
+			Response: `{"values": [{"user": {"uuid": "{1234}", "display_name": "Gob Bluth", "nickname": "gob"}}]}`,
+			UUID:     "{1234}",
+		},
+		"not found": {
+			Status:   http.StatusOK,
+			Response: `{"values": []}`,
+			Error:    "no member of workspace test-workspace has the email",
+		},
+		"restricted by privacy settings": {
+			Status:   http.StatusForbidden,
+			Response: `{"type": "error", "error": {"message": "Forbidden"}}`,
+			Error:    "use their username or uuid instead",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/2.0/workspaces/test-workspace/members" {
+					t.Errorf("unexpected request to %s", r.URL.Path)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if q := r.URL.Query().Get("q"); q != `user.email="gob@example.com"` {
+					t.Errorf("unexpected query %q", q)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(tc.Status)
+				w.Write([]byte(tc.Response))
+			}))
+			defer closeServer()
+
+			u, err := resolveUserByEmail(client, "test-workspace", "gob@example.com")
+			if tc.Error != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.Error) {
+					t.Fatalf("expected error containing %q, got %v", tc.Error, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if u.UUID != tc.UUID {
+				t.Fatalf("expected uuid %s, got %s", tc.UUID, u.UUID)
+			}
+		})
+	}
+}
+
+func TestResolveUserByEmail_escapesQuotes(t *testing.T) {
+	var query string
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("q")
+		w.Write([]byte(`{"values": [{"user": {"uuid": "{1234}"}}]}`))
+	}))
+	defer closeServer()
+
+	if _, err := resolveUserByEmail(client, "test-workspace", `gob" OR user.email!="x@example.com`); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if expected := `user.email="gob\" OR user.email!=\"x@example.com"`; query != expected {
+		t.Fatalf("expected query %s, got %s", expected, query)
+	}
+}
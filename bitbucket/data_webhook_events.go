@@ -0,0 +1,142 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+// WebhookEvent is an event a webhook can subscribe to
+type WebhookEvent struct {
+	Event       string `json:"event"`
+	Category    string `json:"category"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+}
+
+func dataSourceWebhookEvents() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceWebhookEventsRead,
+
+		Schema: map[string]*schema.Schema{
+			"subject_type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "repository",
+				ValidateFunc: validation.StringInSlice([]string{"repository", "workspace"}, false),
+			},
+			"events": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"event": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"category": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"label": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"description": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceWebhookEventsRead(d *schema.ResourceData, m interface{}) error {
+	c := m.(*Client)
+
+	subjectType := d.Get("subject_type").(string)
+
+	webhookEvents, err := getWebhookEvents(c, subjectType)
+	if err != nil {
+		return err
+	}
+
+	events := make([]map[string]interface{}, 0, len(webhookEvents))
+	for _, event := range webhookEvents {
+		events = append(events, map[string]interface{}{
+			"event":       event.Event,
+			"category":    event.Category,
+			"label":       event.Label,
+			"description": event.Description,
+		})
+	}
+
+	d.SetId(subjectType)
+	d.Set("events", events)
+
+	return nil
+}
+
+// getWebhookEvents returns the events webhooks of the subject type can subscribe to, sorted by event
+func getWebhookEvents(c *Client, subjectType string) ([]WebhookEvent, error) {
+	values, err := c.GetPaged(fmt.Sprintf("hook_events/%s", subjectType))
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]WebhookEvent, 0, len(values))
+	for _, value := range values {
+		var event WebhookEvent
+		if err := json.Unmarshal(value, &event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Event < events[j].Event })
+
+	return events, nil
+}
+
+// validateWebhookEvents checks events against the catalog bitbucket has for the subject type, so a
+// typo fails the plan while events added after this provider was released are still accepted. The
+// events are left for bitbucket to check when the catalog can't be read.
+func validateWebhookEvents(m interface{}, subjectType string, events []string) error {
+	c, ok := m.(*Client)
+	if !ok || c == nil || len(events) == 0 {
+		return nil
+	}
+
+	catalog, err := getWebhookEvents(c, subjectType)
+	if err != nil {
+		log.Printf("[WARN] Could not read the %s webhook events to validate against: %s", subjectType, err)
+		return nil
+	}
+
+	known := make(map[string]bool, len(catalog))
+	for _, event := range catalog {
+		known[event.Event] = true
+	}
+
+	var unknown []string
+	for _, event := range events {
+		if !known[event] {
+			unknown = append(unknown, event)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown %s webhook events %s, the data source bitbucket_webhook_events lists the valid ones",
+			subjectType, strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package bitbucket
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+const testWebhookEventsResponse = `{"values": [
+	{"event": "repo:push", "category": "Repository", "label": "Push", "description": "Whenever a repository push occurs"},
+	{"event": "pullrequest:created", "category": "Pull Request", "label": "Created", "description": "Whenever a pull request is created"},
+	{"event": "repo:fork", "category": "Repository", "label": "Fork", "description": "Whenever a repository fork occurs"}
+]}`
+
+func TestWebhookEventsRead(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(map[string]string{
+		"/2.0/hook_events/repository": testWebhookEventsResponse,
+	}))
+	defer closeServer()
+
+	d := schema.TestResourceDataRaw(t, dataSourceWebhookEvents().Schema, map[string]interface{}{})
+
+	if err := dataSourceWebhookEventsRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	events := d.Get("events").([]interface{})
+	if d.Id() != "repository" || len(events) != 3 {
+		t.Fatalf("expected the 3 repository events, got %s %v", d.Id(), events)
+	}
+
+	first := events[0].(map[string]interface{})
+	if first["event"] != "pullrequest:created" || first["category"] != "Pull Request" || first["label"] != "Created" {
+		t.Fatalf("expected the events sorted by event, got %v", events)
+	}
+}
+
+func TestHook_validatesEvents(t *testing.T) {
+	cases := map[string]struct {
+		Events  []interface{}
+		Catalog bool
+		Error   string
+	}{
+		"known events": {
+			Events:  []interface{}{"repo:push", "pullrequest:created"},
+			Catalog: true,
+		},
+		"typo": {
+			Events:  []interface{}{"repo:push", "repo:puhs"},
+			Catalog: true,
+			Error:   "unknown repository webhook events repo:puhs, the data source bitbucket_webhook_events lists the valid ones",
+		},
+		"catalog unavailable": {
+			Events: []interface{}{"repo:puhs"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/2.0/hook_events/repository" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if !tc.Catalog {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte(testWebhookEventsResponse))
+			}))
+			defer closeServer()
+
+			_, err := resourceHook().Diff(nil, testResourceConfig(t, map[string]interface{}{
+				"owner":       "test-owner",
+				"repository":  "test-repo",
+				"url":         "https://example.com/hook",
+				"description": "deploys",
+				"events":      tc.Events,
+			}), client)
+
+			if tc.Error == "" {
+				if err != nil {
+					t.Fatalf("err: %s", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.Error {
+				t.Fatalf("expected error %q, got %v", tc.Error, err)
+			}
+		})
+	}
+}
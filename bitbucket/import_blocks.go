@@ -0,0 +1,60 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"unicode"
+)
+
+// PaginatedRepositories is a paginated list of repositories that the bitbucket api returns
+type PaginatedRepositories struct {
+	Values []Repository `json:"values,omitempty"`
+	Page   int          `json:"page,omitempty"`
+	Size   int          `json:"size,omitempty"`
+	Next   string       `json:"next,omitempty"`
+}
+
+var invalidResourceNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// GenerateImportBlocks returns a terraform import block for every repository in a workspace, this
+// makes it easy to bring existing repositories under terraform from an external tool
+func GenerateImportBlocks(client *Client, workspace string) ([]string, error) {
+	values, err := client.GetPaged(fmt.Sprintf("repositories/%s", workspace))
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]string, 0, len(values))
+	used := make(map[string]bool, len(values))
+	for _, value := range values {
+		var repo Repository
+		if err := json.Unmarshal(value, &repo); err != nil {
+			return nil, err
+		}
+
+		// Slugs that only differ in replaced characters, like my.repo and my_repo, get the same name
+		name := importResourceName(repo.Slug)
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s_%d", importResourceName(repo.Slug), i)
+		}
+		used[name] = true
+
+		blocks = append(blocks, fmt.Sprintf("import {\n  to = bitbucket_repository.%s\n  id = \"%s/%s\"\n}\n",
+			name,
+			workspace,
+			repo.Slug,
+		))
+	}
+
+	return blocks, nil
+}
+
+// importResourceName turns a slug into a valid resource name, slugs can contain dots and start with a digit
+func importResourceName(slug string) string {
+	name := invalidResourceNameChars.ReplaceAllString(slug, "_")
+	if name == "" || (!unicode.IsLetter(rune(name[0])) && name[0] != '_') {
+		name = "repo_" + name
+	}
+	return name
+}
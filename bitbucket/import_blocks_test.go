@@ -0,0 +1,68 @@
+package bitbucket
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateImportBlocks(t *testing.T) {
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2.0/repositories/test-workspace" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Write([]byte(`{"page": 1, "next": "https://api.bitbucket.org/2.0/repositories/test-workspace?page=2",
+				"values": [{"slug": "infrastructure"}, {"slug": "my.website"}]}`))
+		case "2":
+			w.Write([]byte(`{"page": 2, "values": [{"slug": "2fa-service"}]}`))
+		default:
+			t.Errorf("unexpected page %s", r.URL.Query().Get("page"))
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer closeServer()
+
+	blocks, err := GenerateImportBlocks(client, "test-workspace")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"import {\n  to = bitbucket_repository.infrastructure\n  id = \"test-workspace/infrastructure\"\n}\n",
+		"import {\n  to = bitbucket_repository.my_website\n  id = \"test-workspace/my.website\"\n}\n",
+		"import {\n  to = bitbucket_repository.repo_2fa-service\n  id = \"test-workspace/2fa-service\"\n}\n",
+	}
+
+	if !reflect.DeepEqual(blocks, expected) {
+		t.Fatalf("expected %q, got %q", expected, blocks)
+	}
+}
+
+func TestGenerateImportBlocks_collidingSlugs(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(map[string]string{
+		"/2.0/repositories/test-workspace": `{"page": 1, "values": [{"slug": "my.repo"}, {"slug": "my_repo"},
+			{"slug": "my-repo"}, {"slug": "my_repo_2"}]}`,
+	}))
+	defer closeServer()
+
+	blocks, err := GenerateImportBlocks(client, "test-workspace")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"import {\n  to = bitbucket_repository.my_repo\n  id = \"test-workspace/my.repo\"\n}\n",
+		"import {\n  to = bitbucket_repository.my_repo_2\n  id = \"test-workspace/my_repo\"\n}\n",
+		"import {\n  to = bitbucket_repository.my-repo\n  id = \"test-workspace/my-repo\"\n}\n",
+		"import {\n  to = bitbucket_repository.my_repo_2_2\n  id = \"test-workspace/my_repo_2\"\n}\n",
+	}
+
+	if !reflect.DeepEqual(blocks, expected) {
+		t.Fatalf("expected %q, got %q", expected, blocks)
+	}
+}
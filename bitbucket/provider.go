@@ -1,49 +1,374 @@
 package bitbucket
 
 import (
+	"fmt"
+	"io/ioutil"
+	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
 	"github.com/hashicorp/terraform/terraform"
 )
 
 // Provider will create the necessary terraform provider to talk to the Bitbucket APIs you should
-// specify a USERNAME and PASSWORD
+// specify a USERNAME and PASSWORD or a TOKEN
 func Provider() terraform.ResourceProvider {
-	return &schema.Provider{
+	p := &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"username": {
-				Required:    true,
-				Type:        schema.TypeString,
-				DefaultFunc: schema.EnvDefaultFunc("BITBUCKET_USERNAME", nil),
+				Optional: true,
+				Type:     schema.TypeString,
 			},
 			"password": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"token": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"oauth_client_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"oauth_client_secret": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"base_url": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("BITBUCKET_BASE_URL", "https://api.bitbucket.org"),
+				ValidateFunc: validateHTTPURL,
+			},
+			"dry_run": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("BITBUCKET_DRY_RUN", false),
+			},
+			"page_size": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("BITBUCKET_PAGE_SIZE", 100),
+				ValidateFunc: validation.IntBetween(1, 100),
+			},
+			"http_timeout_seconds": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("BITBUCKET_HTTP_TIMEOUT_SECONDS", 60),
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"credentials_file": {
 				Type:        schema.TypeString,
-				Required:    true,
-				DefaultFunc: schema.EnvDefaultFunc("BITBUCKET_PASSWORD", nil),
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("BITBUCKET_CREDENTIALS_FILE", nil),
+			},
+			"check_scopes": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("BITBUCKET_CHECK_SCOPES", false),
+			},
+			"max_retries": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("BITBUCKET_MAX_RETRIES", 3),
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"retry_base_delay": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("BITBUCKET_RETRY_BASE_DELAY", 1),
+				ValidateFunc: validation.IntAtLeast(0),
 			},
 		},
 		ConfigureFunc: providerConfigure,
 		ResourcesMap: map[string]*schema.Resource{
-			"bitbucket_hook":                resourceHook(),
-			"bitbucket_default_reviewers":   resourceDefaultReviewers(),
-			"bitbucket_repository":          resourceRepository(),
-			"bitbucket_repository_variable": resourceRepositoryVariable(),
-			"bitbucket_project":             resourceProject(),
-			"bitbucket_branch_restriction":  resourceBranchRestriction(),
+			"bitbucket_hook":                           resourceHook(),
+			"bitbucket_webhook":                        resourceHook(),
+			"bitbucket_default_reviewers":              resourceDefaultReviewers(),
+			"bitbucket_default_reviewer":               resourceDefaultReviewer(),
+			"bitbucket_repository":                     resourceRepository(),
+			"bitbucket_repository_variable":            resourceRepositoryVariable(),
+			"bitbucket_repository_tags":                resourceRepositoryTags(),
+			"bitbucket_project":                        resourceProject(),
+			"bitbucket_branch_restriction":             resourceBranchRestriction(),
+			"bitbucket_repository_branch_restrictions": resourceRepositoryBranchRestrictions(),
+			"bitbucket_project_branch_restriction":     resourceProjectBranchRestriction(),
+			"bitbucket_deploy_key":                     resourceDeployKey(),
+			"bitbucket_repository_webhooks":            resourceRepositoryWebhooks(),
+			"bitbucket_codeowners":                     resourceCodeowners(),
+			"bitbucket_deployment":                     resourceDeployment(),
+			"bitbucket_deployment_variable":            resourceDeploymentVariable(),
+			"bitbucket_ssh_key":                        resourceSSHKey(),
+			"bitbucket_group_membership":               resourceGroupMembership(),
+			"bitbucket_repository_user_permission":     resourceRepositoryUserPermission(),
+			"bitbucket_repository_group_permission":    resourceRepositoryGroupPermission(),
 		},
 		DataSourcesMap: map[string]*schema.Resource{
-			"bitbucket_user": dataUser(),
+			"bitbucket_user":                           dataUser(),
+			"bitbucket_group":                          dataGroup(),
+			"bitbucket_group_members":                  dataGroupMembers(),
+			"bitbucket_deployment_environment_usage":   dataSourceDeploymentEnvironmentUsage(),
+			"bitbucket_repository_permissions_summary": dataSourceRepositoryPermissionsSummary(),
+			"bitbucket_status":                         dataSourceStatus(),
+			"bitbucket_webhook_events":                 dataSourceWebhookEvents(),
 		},
 	}
+
+	for name, resource := range p.ResourcesMap {
+		resource.Create = createCheckingScope(name, resource.Create)
+	}
+
+	return p
+}
+
+// credentials are what the Client authenticates with, a token, a username and app password or an
+// OAuth consumer key and secret
+type credentials struct {
+	Username          string
+	Password          string
+	Token             string
+	OAuthClientID     string
+	OAuthClientSecret string
+}
+
+// methods lists the ways of authenticating the credentials are complete for
+func (c credentials) methods() []string {
+	var methods []string
+
+	if c.Token != "" {
+		methods = append(methods, "token")
+	}
+	if c.Username != "" && c.Password != "" {
+		methods = append(methods, "username and password")
+	}
+	if c.OAuthClientID != "" && c.OAuthClientSecret != "" {
+		methods = append(methods, "oauth_client_id and oauth_client_secret")
+	}
+
+	return methods
+}
+
+func (c credentials) complete() bool {
+	return len(c.methods()) > 0
+}
+
+// check makes sure exactly one way of authenticating is configured, picking one silently would
+// hide which credentials are really used
+func (c credentials) check(source string) error {
+	if methods := c.methods(); len(methods) > 1 {
+		return fmt.Errorf("Only one way of authenticating with Bitbucket can be configured, %s sets %s",
+			source, strings.Join(methods, ", "))
+	}
+	return nil
+}
+
+// defaultCredentialsFile is where credentials are looked for when nothing else provides them
+func defaultCredentialsFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".bitbucket", "credentials")
+}
+
+// readCredentialsFile reads `key = value` lines for username, password and token, a missing file
+// just means there are no credentials in it
+func readCredentialsFile(path string) (credentials, error) {
+	var creds credentials
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return creds, nil
+	}
+	if err != nil {
+		return creds, fmt.Errorf("Failed to read credentials file %s: %s", path, err)
+	}
+
+	for n, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return creds, fmt.Errorf("Failed to read credentials file %s: line %d isn't `key = value`", path, n+1)
+		}
+
+		value := strings.TrimSpace(parts[1])
+		switch strings.TrimSpace(parts[0]) {
+		case "username":
+			creds.Username = value
+		case "password":
+			creds.Password = value
+		case "token":
+			creds.Token = value
+		case "oauth_client_id":
+			creds.OAuthClientID = value
+		case "oauth_client_secret":
+			creds.OAuthClientSecret = value
+		}
+	}
+
+	return creds, nil
+}
+
+// envCredentials are the credentials the BITBUCKET_* environment variables hold
+func envCredentials() credentials {
+	return credentials{
+		Username:          os.Getenv("BITBUCKET_USERNAME"),
+		Password:          os.Getenv("BITBUCKET_PASSWORD"),
+		Token:             os.Getenv("BITBUCKET_TOKEN"),
+		OAuthClientID:     os.Getenv("BITBUCKET_OAUTH_CLIENT_ID"),
+		OAuthClientSecret: os.Getenv("BITBUCKET_OAUTH_CLIENT_SECRET"),
+	}
+}
+
+// resolveCredentials picks the first complete set of credentials from the provider config, then the
+// BITBUCKET_* environment variables, then the credentials file. The sources aren't mixed, so a token
+// in the provider block is used even when a CI job sets BITBUCKET_USERNAME and BITBUCKET_PASSWORD.
+func resolveCredentials(d *schema.ResourceData) (credentials, error) {
+	creds := credentials{
+		Username:          d.Get("username").(string),
+		Password:          d.Get("password").(string),
+		Token:             d.Get("token").(string),
+		OAuthClientID:     d.Get("oauth_client_id").(string),
+		OAuthClientSecret: d.Get("oauth_client_secret").(string),
+	}
+
+	if creds.complete() {
+		return creds, creds.check("the provider config")
+	}
+
+	if env := envCredentials(); env.complete() {
+		return env, env.check("the environment")
+	}
+
+	path := d.Get("credentials_file").(string)
+	if path == "" {
+		path = defaultCredentialsFile()
+	}
+
+	if path != "" {
+		fileCreds, err := readCredentialsFile(path)
+		if err != nil {
+			return creds, err
+		}
+
+		if fileCreds.complete() {
+			return fileCreds, fileCreds.check(fmt.Sprintf("the credentials file %q", path))
+		}
+	}
+
+	return creds, fmt.Errorf("No Bitbucket credentials found, set a token, a username and password or an "+
+		"oauth_client_id and oauth_client_secret. Checked the provider config, the BITBUCKET_USERNAME, "+
+		"BITBUCKET_PASSWORD, BITBUCKET_TOKEN, BITBUCKET_OAUTH_CLIENT_ID and BITBUCKET_OAUTH_CLIENT_SECRET "+
+		"environment variables and the credentials file %q", path)
 }
 
 func providerConfigure(d *schema.ResourceData) (interface{}, error) {
+	creds, err := resolveCredentials(d)
+	if err != nil {
+		return nil, err
+	}
+
 	client := &Client{
-		Username:   d.Get("username").(string),
-		Password:   d.Get("password").(string),
-		HTTPClient: &http.Client{},
+		Username:          creds.Username,
+		Password:          creds.Password,
+		Token:             creds.Token,
+		OAuthClientID:     creds.OAuthClientID,
+		OAuthClientSecret: creds.OAuthClientSecret,
+		BaseURL:           d.Get("base_url").(string),
+		DryRun:            d.Get("dry_run").(bool),
+		PageSize:          d.Get("page_size").(int),
+		HTTPClient:        &http.Client{Timeout: time.Duration(d.Get("http_timeout_seconds").(int)) * time.Second},
+		MaxRetries:        d.Get("max_retries").(int),
+		RetryBaseDelay:    time.Duration(d.Get("retry_base_delay").(int)) * time.Second,
+		CheckScopes:       d.Get("check_scopes").(bool),
 	}
 
 	return client, nil
 }
+
+// resourceScopes are the scopes each resource needs to be managed, a higher level of the same
+// scope (write over read, admin over write) is just as good
+var resourceScopes = map[string]string{
+	"bitbucket_hook":                           "webhook",
+	"bitbucket_webhook":                        "webhook",
+	"bitbucket_repository_webhooks":            "webhook",
+	"bitbucket_default_reviewers":              "repository:admin",
+	"bitbucket_default_reviewer":               "repository:admin",
+	"bitbucket_repository":                     "repository:admin",
+	"bitbucket_repository_variable":            "pipeline:variable",
+	"bitbucket_repository_tags":                "repository:write",
+	"bitbucket_project":                        "project:admin",
+	"bitbucket_branch_restriction":             "repository:admin",
+	"bitbucket_repository_branch_restrictions": "repository:admin",
+	"bitbucket_project_branch_restriction":     "project:admin",
+	"bitbucket_deploy_key":                     "repository:admin",
+	"bitbucket_codeowners":                     "repository:write",
+	"bitbucket_deployment":                     "pipeline:write",
+	"bitbucket_deployment_variable":            "pipeline:variable",
+	"bitbucket_ssh_key":                        "account:write",
+	"bitbucket_repository_user_permission":     "repository:admin",
+	"bitbucket_repository_group_permission":    "repository:admin",
+}
+
+func hasScope(granted map[string]bool, scope string) bool {
+	if granted[scope] {
+		return true
+	}
+
+	parts := strings.SplitN(scope, ":", 2)
+	switch {
+	case len(parts) == 1:
+		return granted[scope+":write"] || granted[scope+":admin"]
+	case parts[1] == "write":
+		return granted[parts[0]+":admin"]
+	}
+	return false
+}
+
+// resourceScopeWarning describes the scope the credentials lack to manage the resource, it only ever
+// warns so a missing scope shows up before an apply fails halfway
+func resourceScopeWarning(client *Client, resource string) string {
+	scope, ok := resourceScopes[resource]
+	if !ok {
+		return ""
+	}
+
+	granted, err := client.grantedScopes()
+	if err != nil {
+		return fmt.Sprintf("Could not check the scopes of the Bitbucket credentials: %s", err)
+	}
+	if granted == nil {
+		return "Bitbucket didn't report the scopes of the credentials, they can't be checked"
+	}
+
+	if hasScope(granted, scope) {
+		return ""
+	}
+	return fmt.Sprintf("The Bitbucket credentials don't have the %s scope, managing %s will fail", scope, resource)
+}
+
+// createCheckingScope checks the scope of the resource before it is created when check_scopes is
+// set, only the resources in the config are created so credentials aren't warned about the others
+func createCheckingScope(resource string, create schema.CreateFunc) schema.CreateFunc {
+	return func(d *schema.ResourceData, m interface{}) error {
+		if client, ok := m.(*Client); ok && client.CheckScopes {
+			if warning := resourceScopeWarning(client, resource); warning != "" {
+				log.Printf("[WARN] %s", warning)
+			}
+		}
+		return create(d, m)
+	}
+}
@@ -1,10 +1,19 @@
 package bitbucket
 
 import (
-	"github.com/hashicorp/terraform/helper/schema"
-	"github.com/hashicorp/terraform/terraform"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
 )
 
 const testRepo string = "test-repo"
@@ -42,3 +51,410 @@ func testAccPreCheck(t *testing.T) {
 		t.Fatal("BITBUCKET_TEAM must be set for acceptence tests")
 	}
 }
+
+// testTransport sends every request to a local test server instead of bitbucket
+type testTransport struct {
+	URL *url.URL
+}
+
+func (t testTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = t.URL.Scheme
+	req.URL.Host = t.URL.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// testClient returns a Client talking to handler, call the returned func to shut the server down
+func testClient(t *testing.T, handler http.Handler) (*Client, func()) {
+	server := httptest.NewServer(handler)
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	client := &Client{
+		Username:   "user",
+		Password:   "pass",
+		HTTPClient: &http.Client{Transport: testTransport{URL: serverURL}},
+	}
+
+	return client, server.Close
+}
+
+// testResponses serves a fixed JSON body per request path and a 404 for anything else
+func testResponses(responses map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"type": "error", "error": {"message": "Not found"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}
+}
+
+func testResourceConfig(t *testing.T, raw map[string]interface{}) *terraform.ResourceConfig {
+	rawConfig, err := config.NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return terraform.NewResourceConfig(rawConfig)
+}
+
+func testProviderConfigure(t *testing.T, raw map[string]interface{}) *Client {
+	p := Provider().(*schema.Provider)
+	if err := p.Configure(testResourceConfig(t, raw)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return p.Meta().(*Client)
+}
+
+func TestProvider_retrySettings(t *testing.T) {
+	cases := map[string]struct {
+		Env        map[string]string
+		Config     map[string]interface{}
+		MaxRetries int
+		BaseDelay  time.Duration
+	}{
+		"defaults": {
+			MaxRetries: 3,
+			BaseDelay:  time.Second,
+		},
+		"env only": {
+			Env: map[string]string{
+				"BITBUCKET_MAX_RETRIES":      "7",
+				"BITBUCKET_RETRY_BASE_DELAY": "2",
+			},
+			MaxRetries: 7,
+			BaseDelay:  2 * time.Second,
+		},
+		"config only": {
+			Config: map[string]interface{}{
+				"max_retries":      5,
+				"retry_base_delay": 4,
+			},
+			MaxRetries: 5,
+			BaseDelay:  4 * time.Second,
+		},
+		"config takes precedence over env": {
+			Env: map[string]string{
+				"BITBUCKET_MAX_RETRIES":      "7",
+				"BITBUCKET_RETRY_BASE_DELAY": "2",
+			},
+			Config: map[string]interface{}{
+				"max_retries":      0,
+				"retry_base_delay": 4,
+			},
+			MaxRetries: 0,
+			BaseDelay:  4 * time.Second,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			for _, k := range []string{"BITBUCKET_MAX_RETRIES", "BITBUCKET_RETRY_BASE_DELAY"} {
+				defer os.Setenv(k, os.Getenv(k))
+				os.Unsetenv(k)
+			}
+			for k, v := range tc.Env {
+				os.Setenv(k, v)
+			}
+
+			raw := map[string]interface{}{
+				"username": "user",
+				"password": "pass",
+			}
+			for k, v := range tc.Config {
+				raw[k] = v
+			}
+
+			client := testProviderConfigure(t, raw)
+
+			if client.MaxRetries != tc.MaxRetries {
+				t.Fatalf("expected max retries %d, got %d", tc.MaxRetries, client.MaxRetries)
+			}
+			if client.RetryBaseDelay != tc.BaseDelay {
+				t.Fatalf("expected retry base delay %s, got %s", tc.BaseDelay, client.RetryBaseDelay)
+			}
+		})
+	}
+}
+
+func TestProvider_credentialSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bitbucket")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	credentialsFile := filepath.Join(dir, "credentials")
+	content := "# written by the test\nusername = file-user\npassword = file-pass\n"
+	if err := ioutil.WriteFile(credentialsFile, []byte(content), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	oauthCredentialsFile := filepath.Join(dir, "oauth")
+	content = "oauth_client_id = file-id\noauth_client_secret = file-secret\n"
+	if err := ioutil.WriteFile(oauthCredentialsFile, []byte(content), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := map[string]struct {
+		Env      map[string]string
+		Config   map[string]interface{}
+		Expected credentials
+		Error    string
+	}{
+		"config": {
+			Config:   map[string]interface{}{"username": "config-user", "password": "config-pass"},
+			Expected: credentials{Username: "config-user", Password: "config-pass"},
+		},
+		"env": {
+			Env:      map[string]string{"BITBUCKET_USERNAME": "env-user", "BITBUCKET_PASSWORD": "env-pass"},
+			Expected: credentials{Username: "env-user", Password: "env-pass"},
+		},
+		"env token": {
+			Env:      map[string]string{"BITBUCKET_TOKEN": "env-token"},
+			Expected: credentials{Token: "env-token"},
+		},
+		"credentials file": {
+			Config:   map[string]interface{}{"credentials_file": credentialsFile},
+			Expected: credentials{Username: "file-user", Password: "file-pass"},
+		},
+		"config takes precedence over env": {
+			Env:      map[string]string{"BITBUCKET_USERNAME": "env-user", "BITBUCKET_PASSWORD": "env-pass"},
+			Config:   map[string]interface{}{"username": "config-user", "password": "config-pass"},
+			Expected: credentials{Username: "config-user", Password: "config-pass"},
+		},
+		"env takes precedence over the credentials file": {
+			Env:      map[string]string{"BITBUCKET_TOKEN": "env-token"},
+			Config:   map[string]interface{}{"credentials_file": credentialsFile},
+			Expected: credentials{Token: "env-token"},
+		},
+		"incomplete env falls back to the credentials file": {
+			Env:      map[string]string{"BITBUCKET_USERNAME": "env-user"},
+			Config:   map[string]interface{}{"credentials_file": credentialsFile},
+			Expected: credentials{Username: "file-user", Password: "file-pass"},
+		},
+		"env oauth": {
+			Env:      map[string]string{"BITBUCKET_OAUTH_CLIENT_ID": "env-id", "BITBUCKET_OAUTH_CLIENT_SECRET": "env-secret"},
+			Expected: credentials{OAuthClientID: "env-id", OAuthClientSecret: "env-secret"},
+		},
+		"oauth in the credentials file": {
+			Config:   map[string]interface{}{"credentials_file": oauthCredentialsFile},
+			Expected: credentials{OAuthClientID: "file-id", OAuthClientSecret: "file-secret"},
+		},
+		"config token takes precedence over env username and password": {
+			Env:      map[string]string{"BITBUCKET_USERNAME": "env-user", "BITBUCKET_PASSWORD": "env-pass"},
+			Config:   map[string]interface{}{"token": "config-token"},
+			Expected: credentials{Token: "config-token"},
+		},
+		"config oauth takes precedence over env username and password": {
+			Env:      map[string]string{"BITBUCKET_USERNAME": "env-user", "BITBUCKET_PASSWORD": "env-pass"},
+			Config:   map[string]interface{}{"oauth_client_id": "config-id", "oauth_client_secret": "config-secret"},
+			Expected: credentials{OAuthClientID: "config-id", OAuthClientSecret: "config-secret"},
+		},
+		"incomplete config falls back to env": {
+			Env:      map[string]string{"BITBUCKET_TOKEN": "env-token"},
+			Config:   map[string]interface{}{"username": "config-user"},
+			Expected: credentials{Token: "env-token"},
+		},
+		"more than one method in the config": {
+			Config: map[string]interface{}{"token": "config-token", "oauth_client_id": "config-id", "oauth_client_secret": "config-secret"},
+			Error:  "the provider config sets token, oauth_client_id and oauth_client_secret",
+		},
+		"more than one method in the environment": {
+			Env:   map[string]string{"BITBUCKET_USERNAME": "env-user", "BITBUCKET_PASSWORD": "env-pass", "BITBUCKET_TOKEN": "env-token"},
+			Error: "the environment sets token, username and password",
+		},
+		"nothing found": {
+			Config: map[string]interface{}{"credentials_file": filepath.Join(dir, "missing")},
+			Error:  "Checked the provider config, the BITBUCKET_USERNAME, BITBUCKET_PASSWORD, BITBUCKET_TOKEN",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			for _, k := range []string{"BITBUCKET_USERNAME", "BITBUCKET_PASSWORD", "BITBUCKET_TOKEN",
+				"BITBUCKET_OAUTH_CLIENT_ID", "BITBUCKET_OAUTH_CLIENT_SECRET", "BITBUCKET_CREDENTIALS_FILE"} {
+				defer os.Setenv(k, os.Getenv(k))
+				os.Unsetenv(k)
+			}
+			for k, v := range tc.Env {
+				os.Setenv(k, v)
+			}
+
+			p := Provider().(*schema.Provider)
+			err := p.Configure(testResourceConfig(t, tc.Config))
+
+			if tc.Error != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.Error) {
+					t.Fatalf("expected error containing %q, got %v", tc.Error, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			client := p.Meta().(*Client)
+			creds := credentials{
+				Username:          client.Username,
+				Password:          client.Password,
+				Token:             client.Token,
+				OAuthClientID:     client.OAuthClientID,
+				OAuthClientSecret: client.OAuthClientSecret,
+			}
+			if creds != tc.Expected {
+				t.Fatalf("expected %+v, got %+v", tc.Expected, creds)
+			}
+		})
+	}
+}
+
+func TestResourceScopeWarning(t *testing.T) {
+	cases := map[string]struct {
+		Resource string
+		Scopes   string
+		Warning  string
+	}{
+		"scope granted": {
+			Resource: "bitbucket_repository",
+			Scopes:   "repository:admin, webhook",
+		},
+		"admin covers write": {
+			Resource: "bitbucket_repository_tags",
+			Scopes:   "repository:admin",
+		},
+		"other resources aren't checked": {
+			Resource: "bitbucket_repository",
+			Scopes:   "repository:admin",
+		},
+		"missing scope": {
+			Resource: "bitbucket_deployment_variable",
+			Scopes:   "repository:admin, webhook",
+			Warning:  "The Bitbucket credentials don't have the pipeline:variable scope, managing bitbucket_deployment_variable will fail",
+		},
+		"scopes not reported": {
+			Resource: "bitbucket_repository",
+			Warning:  "Bitbucket didn't report the scopes of the credentials, they can't be checked",
+		},
+		"resource without a scope": {
+			Resource: "bitbucket_group_membership",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/2.0/user" {
+					t.Errorf("unexpected request to %s", r.URL.Path)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if tc.Scopes != "" {
+					w.Header().Set("X-OAuth-Scopes", tc.Scopes)
+				}
+				w.Write([]byte(`{"username": "gob"}`))
+			}))
+			defer closeServer()
+
+			if warning := resourceScopeWarning(client, tc.Resource); warning != tc.Warning {
+				t.Fatalf("expected warning %q, got %q", tc.Warning, warning)
+			}
+		})
+	}
+}
+
+func TestCreateCheckingScope(t *testing.T) {
+	lookups := 0
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		w.Header().Set("X-OAuth-Scopes", "repository:admin")
+		w.Write([]byte(`{"username": "gob"}`))
+	}))
+	defer closeServer()
+
+	created := 0
+	create := createCheckingScope("bitbucket_repository", func(d *schema.ResourceData, m interface{}) error {
+		created++
+		return nil
+	})
+
+	for _, checkScopes := range []bool{false, true, true} {
+		client.CheckScopes = checkScopes
+		if err := create(nil, client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	if created != 3 {
+		t.Fatalf("expected every create to go through, got %d", created)
+	}
+	if lookups != 1 {
+		t.Fatalf("expected the scopes to be looked up once and only with check_scopes, got %d lookups", lookups)
+	}
+}
+
+func TestProvider_deleteAfterRepository(t *testing.T) {
+	// Every request 404s, as it does once the repository the resources belong to was deleted
+	client, closeServer := testClient(t, testResponses(nil))
+	defer closeServer()
+
+	cases := map[string]struct {
+		Resource   *schema.Resource
+		ID         string
+		Attributes map[string]string
+	}{
+		"bitbucket_hook": {
+			Resource:   resourceHook(),
+			ID:         "{hook}",
+			Attributes: map[string]string{"owner": "test-owner", "repository": "test-repo"},
+		},
+		"bitbucket_repository_variable": {
+			Resource:   resourceRepositoryVariable(),
+			ID:         "test-owner/test-repo/KEY",
+			Attributes: map[string]string{"repository": "test-owner/test-repo", "uuid": "{variable}"},
+		},
+		"bitbucket_deploy_key": {
+			Resource:   resourceDeployKey(),
+			ID:         "test-owner/test-repo/1",
+			Attributes: map[string]string{"owner": "test-owner", "repository": "test-repo", "key_id": "1"},
+		},
+		"bitbucket_branch_restriction": {
+			Resource:   resourceBranchRestriction(),
+			ID:         "1",
+			Attributes: map[string]string{"owner": "test-owner", "repository": "test-repo"},
+		},
+		"bitbucket_default_reviewers": {
+			Resource: resourceDefaultReviewers(),
+			ID:       "test-owner/test-repo/reviewers",
+			Attributes: map[string]string{
+				"owner":       "test-owner",
+				"repository":  "test-repo",
+				"reviewers.#": "1",
+				"reviewers.1": "{reviewer}",
+			},
+		},
+		"bitbucket_repository_webhooks": {
+			Resource:   resourceRepositoryWebhooks(),
+			ID:         "test-owner/test-repo",
+			Attributes: map[string]string{"owner": "test-owner", "repository": "test-repo"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := tc.Resource.Data(&terraform.InstanceState{ID: tc.ID, Attributes: tc.Attributes})
+
+			if err := tc.Resource.Delete(d, client); err != nil {
+				t.Fatalf("expected deleting after the repository to succeed, got %s", err)
+			}
+		})
+	}
+}
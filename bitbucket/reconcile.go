@@ -0,0 +1,77 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// getRepositoryCollection lists a collection under a repository, the collection of a repository that
+// is gone is empty
+func getRepositoryCollection(client *Client, owner, repoSlug, collection string) ([]json.RawMessage, error) {
+	values, err := client.GetPaged(fmt.Sprintf("repositories/%s/%s/%s", owner, repoSlug, collection))
+
+	if apiErr, ok := err.(Error); ok && apiErr.StatusCode == 404 {
+		return nil, nil
+	}
+
+	return values, err
+}
+
+// reconcile turns a collection holding the existing items into one holding the desired items, both
+// given by key. Existing items nobody wants are removed and desired items that don't exist yet are
+// created, items with the same key are matched one for one. The callbacks get the index of the item
+// in its slice.
+func reconcile(existing, desired []string, remove, create func(i int) error) error {
+	wanted := make(map[string]int)
+	for _, key := range desired {
+		wanted[key]++
+	}
+
+	for i, key := range existing {
+		if wanted[key] > 0 {
+			wanted[key]--
+			continue
+		}
+
+		if err := remove(i); err != nil {
+			return err
+		}
+	}
+
+	for i, key := range desired {
+		if wanted[key] == 0 {
+			continue
+		}
+		wanted[key]--
+
+		if err := create(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isManaged tells whether a resource that owns a whole collection owns an item, when ignoreKey is set
+// it only owns the items whose value starts with the prefix in prefixKey
+func isManaged(d *schema.ResourceData, ignoreKey, prefixKey, value string) bool {
+	return !d.Get(ignoreKey).(bool) || strings.HasPrefix(value, d.Get(prefixKey).(string))
+}
+
+// managedPrefix returns the prefix every configured item must start with, since one without it would
+// be created and then never read back, or "" when ignoreKey isn't set
+func managedPrefix(d *schema.ResourceDiff, ignoreKey, prefixKey string) (string, error) {
+	if !d.Get(ignoreKey).(bool) {
+		return "", nil
+	}
+
+	prefix := d.Get(prefixKey).(string)
+	if prefix == "" {
+		return "", fmt.Errorf("%s must be set when %s is true", prefixKey, ignoreKey)
+	}
+
+	return prefix, nil
+}
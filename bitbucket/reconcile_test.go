@@ -0,0 +1,35 @@
+package bitbucket
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReconcile(t *testing.T) {
+	existing := []string{"a", "b", "b", "c"}
+	desired := []string{"b", "c", "c", "d"}
+
+	var removed, created []string
+	err := reconcile(existing, desired,
+		func(i int) error {
+			removed = append(removed, existing[i])
+			return nil
+		},
+		func(i int) error {
+			created = append(created, desired[i])
+			return nil
+		},
+	)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(removed, []string{"a", "b"}) {
+		t.Errorf("expected a and one b to be removed, got %v", removed)
+	}
+
+	if !reflect.DeepEqual(created, []string{"c", "d"}) {
+		t.Errorf("expected one c and d to be created, got %v", created)
+	}
+}
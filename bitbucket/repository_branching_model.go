@@ -0,0 +1,414 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform/helper/hashcode"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+// BranchingModel is the branching model settings of a repository
+type BranchingModel struct {
+	Development *BranchingModelBranch `json:"development,omitempty"`
+	Production  *BranchingModelBranch `json:"production,omitempty"`
+	BranchTypes []BranchType          `json:"branch_types"`
+}
+
+// BranchingModelBranch is the development or production branch of a branching model
+type BranchingModelBranch struct {
+	Name          string `json:"name,omitempty"`
+	UseMainbranch bool   `json:"use_mainbranch"`
+	// Enabled is only used by the production branch
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// BranchType is the prefix branches of a kind, e.g. feature, are created with
+type BranchType struct {
+	Kind    string `json:"kind"`
+	Enabled bool   `json:"enabled"`
+	Prefix  string `json:"prefix,omitempty"`
+}
+
+func branchingModelSettingsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"development": {
+					Type:     schema.TypeList,
+					Optional: true,
+					MaxItems: 1,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"name": {
+								Type:     schema.TypeString,
+								Optional: true,
+							},
+							"use_mainbranch": {
+								Type:     schema.TypeBool,
+								Optional: true,
+								Default:  false,
+							},
+						},
+					},
+				},
+				"production": {
+					Type:     schema.TypeList,
+					Optional: true,
+					MaxItems: 1,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"enabled": {
+								Type:     schema.TypeBool,
+								Optional: true,
+								Default:  true,
+							},
+							"name": {
+								Type:     schema.TypeString,
+								Optional: true,
+							},
+							"use_mainbranch": {
+								Type:     schema.TypeBool,
+								Optional: true,
+								Default:  false,
+							},
+						},
+					},
+				},
+				"default_prefixes": {
+					Type:     schema.TypeMap,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"branch_types": {
+					Type:     schema.TypeSet,
+					Optional: true,
+					Set:      branchTypeHash,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"kind": {
+								Type:     schema.TypeString,
+								Required: true,
+								ValidateFunc: validation.StringInSlice([]string{
+									"feature",
+									"bugfix",
+									"release",
+									"hotfix",
+								}, false),
+							},
+							"enabled": {
+								Type:     schema.TypeBool,
+								Optional: true,
+								Default:  true,
+							},
+							"prefix": {
+								Type:     schema.TypeString,
+								Optional: true,
+								Computed: true,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// branchTypeHash keys branch types on their kind, so the order bitbucket returns them in doesn't
+// matter and a changed prefix shows up as a change of that kind rather than a new entry
+func branchTypeHash(v interface{}) int {
+	return hashcode.String(v.(map[string]interface{})["kind"].(string))
+}
+
+func expandBranchingModelBranch(v interface{}) *BranchingModelBranch {
+	blocks := v.([]interface{})
+	if len(blocks) == 0 || blocks[0] == nil {
+		return nil
+	}
+
+	m := blocks[0].(map[string]interface{})
+	branch := &BranchingModelBranch{
+		Name:          m["name"].(string),
+		UseMainbranch: m["use_mainbranch"].(bool),
+	}
+
+	if enabled, ok := m["enabled"]; ok {
+		e := enabled.(bool)
+		branch.Enabled = &e
+	}
+
+	return branch
+}
+
+// branchTypePrefix is the prefix a branch type without one is created with, the one in default_prefixes
+// for its kind or else the standard bitbucket prefix, e.g. feature/
+func branchTypePrefix(kind string, defaults map[string]interface{}) string {
+	if prefix, ok := defaults[kind].(string); ok && prefix != "" {
+		return prefix
+	}
+
+	return kind + "/"
+}
+
+func expandBranchTypes(v interface{}, defaults map[string]interface{}) []BranchType {
+	branchTypes := make([]BranchType, 0, len(v.(*schema.Set).List()))
+
+	for _, item := range v.(*schema.Set).List() {
+		m := item.(map[string]interface{})
+
+		prefix := m["prefix"].(string)
+		if prefix == "" {
+			prefix = branchTypePrefix(m["kind"].(string), defaults)
+		}
+
+		branchTypes = append(branchTypes, BranchType{
+			Kind:    m["kind"].(string),
+			Enabled: m["enabled"].(bool),
+			Prefix:  prefix,
+		})
+	}
+
+	return branchTypes
+}
+
+// expandBranchingModel builds the settings to PUT from the configured block, a development or
+// production branch left out is reset and branch types that were removed from the config are sent
+// disabled so they don't linger on bitbucket
+func expandBranchingModel(old, new map[string]interface{}) *BranchingModel {
+	model := &BranchingModel{
+		Development: expandBranchingModelBranch(new["development"]),
+		Production:  expandBranchingModelBranch(new["production"]),
+		BranchTypes: expandBranchTypes(new["branch_types"], new["default_prefixes"].(map[string]interface{})),
+	}
+
+	if model.Development == nil {
+		model.Development = &BranchingModelBranch{UseMainbranch: true}
+	}
+
+	if model.Production == nil {
+		disabled := false
+		model.Production = &BranchingModelBranch{Enabled: &disabled}
+	}
+
+	configured := make(map[string]bool)
+	for _, branchType := range model.BranchTypes {
+		configured[branchType.Kind] = true
+	}
+
+	if old != nil {
+		for _, branchType := range expandBranchTypes(old["branch_types"], nil) {
+			if !configured[branchType.Kind] {
+				model.BranchTypes = append(model.BranchTypes, BranchType{Kind: branchType.Kind, Enabled: false})
+			}
+		}
+	}
+
+	return model
+}
+
+// flattenBranchingModel turns the settings bitbucket returns into the block, the development and
+// production branches are only tracked when configured or changed from their defaults and only the
+// configured branch types are tracked, so defaults don't show up as drift
+func flattenBranchingModel(model *BranchingModel, configured map[string]interface{}) []map[string]interface{} {
+	settings := make(map[string]interface{})
+
+	development := model.Development
+	if development != nil && (!development.UseMainbranch || len(configured["development"].([]interface{})) > 0) {
+		settings["development"] = []map[string]interface{}{{
+			"name":           development.Name,
+			"use_mainbranch": development.UseMainbranch,
+		}}
+	}
+
+	production := model.Production
+	productionEnabled := production != nil && production.Enabled != nil && *production.Enabled
+	if production != nil && (productionEnabled || len(configured["production"].([]interface{})) > 0) {
+		settings["production"] = []map[string]interface{}{{
+			"enabled":        productionEnabled,
+			"name":           production.Name,
+			"use_mainbranch": production.UseMainbranch,
+		}}
+	}
+
+	remote := make(map[string]BranchType)
+	for _, branchType := range model.BranchTypes {
+		remote[branchType.Kind] = branchType
+	}
+
+	var branchTypes []interface{}
+	for _, branchType := range expandBranchTypes(configured["branch_types"], nil) {
+		remoteType, ok := remote[branchType.Kind]
+		if !ok {
+			remoteType = BranchType{Kind: branchType.Kind}
+		}
+
+		branchTypes = append(branchTypes, map[string]interface{}{
+			"kind":    remoteType.Kind,
+			"enabled": remoteType.Enabled,
+			"prefix":  remoteType.Prefix,
+		})
+	}
+	settings["branch_types"] = schema.NewSet(branchTypeHash, branchTypes)
+	settings["default_prefixes"] = configured["default_prefixes"]
+
+	return []map[string]interface{}{settings}
+}
+
+func branchingModelSettingsURL(owner, repoSlug string) string {
+	return fmt.Sprintf("repositories/%s/%s/branching-model/settings", owner, repoSlug)
+}
+
+func getBranchingModel(client *Client, owner, repoSlug string) (*BranchingModel, error) {
+	settingsReq, err := client.Get(branchingModelSettingsURL(owner, repoSlug))
+	if err != nil {
+		return nil, err
+	}
+
+	var model BranchingModel
+
+	decodeerr := json.NewDecoder(settingsReq.Body).Decode(&model)
+	if decodeerr != nil {
+		return nil, decodeerr
+	}
+
+	return &model, nil
+}
+
+// setRepositoryBranchingModel PUTs the configured branching model, removing the block leaves the
+// settings as they are
+func setRepositoryBranchingModel(d *schema.ResourceData, client *Client, repoSlug string) error {
+	old, new := d.GetChange("branching_model_settings")
+
+	newBlocks := new.([]interface{})
+	if len(newBlocks) == 0 {
+		return nil
+	}
+
+	var oldSettings map[string]interface{}
+	if oldBlocks := old.([]interface{}); len(oldBlocks) > 0 && oldBlocks[0] != nil {
+		oldSettings = oldBlocks[0].(map[string]interface{})
+	}
+
+	bytedata, err := json.Marshal(expandBranchingModel(oldSettings, branchingModelSettings(newBlocks[0])))
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Put(branchingModelSettingsURL(d.Get("owner").(string), repoSlug), bytes.NewBuffer(bytedata))
+
+	return err
+}
+
+// branchingModelSettings is the configured block, an empty block comes through as nil
+func branchingModelSettings(block interface{}) map[string]interface{} {
+	if block == nil {
+		return map[string]interface{}{
+			"development":      []interface{}{},
+			"production":       []interface{}{},
+			"branch_types":     schema.NewSet(branchTypeHash, nil),
+			"default_prefixes": map[string]interface{}{},
+		}
+	}
+
+	return block.(map[string]interface{})
+}
+
+// readRepositoryBranchingModel refreshes the branching model block, it is only read when configured
+func readRepositoryBranchingModel(d *schema.ResourceData, client *Client, repoSlug string) error {
+	blocks := d.Get("branching_model_settings").([]interface{})
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	model, err := getBranchingModel(client, d.Get("owner").(string), repoSlug)
+	if err != nil {
+		return err
+	}
+
+	d.Set("branching_model_settings", flattenBranchingModel(model, branchingModelSettings(blocks[0])))
+
+	projectKey := d.Get("project_key").(string)
+	if projectKey == "" {
+		d.Set("branching_model_matches_project", false)
+		return nil
+	}
+
+	projectModel, err := getProjectBranchingModel(client, d.Get("owner").(string), projectKey)
+	if err != nil {
+		return err
+	}
+
+	matches := projectModel != nil && branchingModelsMatch(model, projectModel)
+	if matches {
+		log.Printf("[WARN] The branching model of %s matches the default of project %s, "+
+			"branching_model_settings can be removed to inherit it", d.Id(), projectKey)
+	}
+	d.Set("branching_model_matches_project", matches)
+
+	return nil
+}
+
+// getProjectBranchingModel returns the branching model repositories in the project inherit, or nil when
+// the project has none
+func getProjectBranchingModel(client *Client, workspace, projectKey string) (*BranchingModel, error) {
+	settingsReq, err := client.Get(fmt.Sprintf("workspaces/%s/projects/%s/branching-model/settings",
+		workspace,
+		projectKey,
+	))
+
+	if settingsReq != nil && settingsReq.StatusCode == 404 {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var model BranchingModel
+
+	decodeerr := json.NewDecoder(settingsReq.Body).Decode(&model)
+	if decodeerr != nil {
+		return nil, decodeerr
+	}
+
+	return &model, nil
+}
+
+func branchingModelBranchesMatch(a, b *BranchingModelBranch) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	aEnabled := a.Enabled != nil && *a.Enabled
+	bEnabled := b.Enabled != nil && *b.Enabled
+
+	return a.Name == b.Name && a.UseMainbranch == b.UseMainbranch && aEnabled == bEnabled
+}
+
+// branchingModelsMatch compares two branching models, the order of the branch types doesn't matter
+func branchingModelsMatch(a, b *BranchingModel) bool {
+	if !branchingModelBranchesMatch(a.Development, b.Development) ||
+		!branchingModelBranchesMatch(a.Production, b.Production) ||
+		len(a.BranchTypes) != len(b.BranchTypes) {
+		return false
+	}
+
+	branchTypes := make(map[string]BranchType)
+	for _, branchType := range a.BranchTypes {
+		branchTypes[branchType.Kind] = branchType
+	}
+
+	for _, branchType := range b.BranchTypes {
+		if other, ok := branchTypes[branchType.Kind]; !ok || other != branchType {
+			return false
+		}
+	}
+
+	return true
+}
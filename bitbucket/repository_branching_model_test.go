@@ -0,0 +1,480 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// testBranchingModelServer serves a repository whose branching model settings can be PUT, settings
+// are returned with the branch types reversed and every kind present like bitbucket does
+func testBranchingModelServer(settings *BranchingModel) http.HandlerFunc {
+	responses := testRepositoryResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo", "scm": "git", "fork_policy": "allow_forks", "is_private": true}`,
+	})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2.0/repositories/test-owner/test-repo/branching-model/settings" {
+			testResponses(responses)(w, r)
+			return
+		}
+
+		if r.Method == "PUT" {
+			var sent BranchingModel
+			json.NewDecoder(r.Body).Decode(&sent)
+
+			if sent.Development != nil {
+				settings.Development = sent.Development
+			}
+			if sent.Production != nil {
+				settings.Production = sent.Production
+			}
+			for _, sentType := range sent.BranchTypes {
+				for i := range settings.BranchTypes {
+					if settings.BranchTypes[i].Kind == sentType.Kind {
+						settings.BranchTypes[i] = sentType
+					}
+				}
+			}
+		}
+
+		reversed := *settings
+		reversed.BranchTypes = nil
+		for i := len(settings.BranchTypes) - 1; i >= 0; i-- {
+			reversed.BranchTypes = append(reversed.BranchTypes, settings.BranchTypes[i])
+		}
+		json.NewEncoder(w).Encode(reversed)
+	}
+}
+
+func TestRepositoryBranchingModel_branchTypesOrder(t *testing.T) {
+	settings := &BranchingModel{
+		Development: &BranchingModelBranch{UseMainbranch: true},
+		BranchTypes: []BranchType{
+			{Kind: "bugfix", Enabled: true, Prefix: "bugfix/"},
+			{Kind: "feature", Enabled: true, Prefix: "feature/"},
+			{Kind: "hotfix", Enabled: true, Prefix: "hotfix/"},
+			{Kind: "release", Enabled: true, Prefix: "release/"},
+		},
+	}
+
+	client, closeServer := testClient(t, testBranchingModelServer(settings))
+	defer closeServer()
+
+	configs := []map[string]interface{}{
+		{"kind": "release", "prefix": "rel/"},
+		{"kind": "feature", "prefix": "feat/"},
+	}
+	reordered := []map[string]interface{}{configs[1], configs[0]}
+
+	raw := func(branchTypes []map[string]interface{}) map[string]interface{} {
+		types := make([]interface{}, 0, len(branchTypes))
+		for _, branchType := range branchTypes {
+			types = append(types, branchType)
+		}
+
+		return map[string]interface{}{
+			"owner": "test-owner",
+			"name":  "test-repo",
+			"branching_model_settings": []interface{}{
+				map[string]interface{}{
+					"development": []interface{}{
+						map[string]interface{}{"use_mainbranch": true},
+					},
+					"branch_types": types,
+				},
+			},
+		}
+	}
+
+	r := resourceRepository()
+	state := &terraform.InstanceState{
+		ID: "test-owner/test-repo",
+		Attributes: map[string]string{
+			"owner":             "test-owner",
+			"name":              "test-repo",
+			"slug":              "test-repo",
+			"scm":               "git",
+			"fork_policy":       "allow_forks",
+			"is_private":        "true",
+			"has_wiki":          "false",
+			"has_issues":        "false",
+			"archived":          "false",
+			"pipelines_enabled": "false",
+		},
+	}
+
+	diff, err := r.Diff(state, testResourceConfig(t, raw(configs)), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err = r.Apply(state, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, branchType := range settings.BranchTypes {
+		expected := map[string]string{"bugfix": "bugfix/", "feature": "feat/", "hotfix": "hotfix/", "release": "rel/"}
+		if branchType.Prefix != expected[branchType.Kind] {
+			t.Fatalf("expected %s to have prefix %s, got %s", branchType.Kind, expected[branchType.Kind], branchType.Prefix)
+		}
+	}
+
+	if n := state.Attributes["branching_model_settings.0.branch_types.#"]; n != "2" {
+		t.Fatalf("expected only the 2 configured branch types in state, got %s", n)
+	}
+
+	for _, config := range [][]map[string]interface{}{configs, reordered} {
+		state, err = r.Refresh(state, client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		diff, err = r.Diff(state, testResourceConfig(t, raw(config)), client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !diff.Empty() {
+			t.Fatalf("expected no diff, got %#v", diff.Attributes)
+		}
+	}
+
+	// Removing a kind disables it rather than leaving it behind
+	diff, err = r.Diff(state, testResourceConfig(t, raw(configs[:1])), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err = r.Apply(state, diff, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, branchType := range settings.BranchTypes {
+		if branchType.Kind == "feature" && branchType.Enabled {
+			t.Fatal("expected the removed feature branch type to be disabled")
+		}
+	}
+}
+
+func TestRepositoryBranchingModel_matchesProject(t *testing.T) {
+	repoSettings := `{
+		"development": {"use_mainbranch": true},
+		"production": {"enabled": false, "use_mainbranch": false},
+		"branch_types": [{"kind": "feature", "enabled": true, "prefix": "feature/"}, {"kind": "release", "enabled": true, "prefix": "release/"}]
+	}`
+
+	cases := map[string]struct {
+		ProjectSettings string
+		Matches         bool
+	}{
+		"matching": {
+			ProjectSettings: `{
+				"development": {"use_mainbranch": true},
+				"production": {"enabled": false, "use_mainbranch": false},
+				"branch_types": [{"kind": "release", "enabled": true, "prefix": "release/"}, {"kind": "feature", "enabled": true, "prefix": "feature/"}]
+			}`,
+			Matches: true,
+		},
+		"differing": {
+			ProjectSettings: `{
+				"development": {"use_mainbranch": true},
+				"production": {"enabled": false, "use_mainbranch": false},
+				"branch_types": [{"kind": "release", "enabled": true, "prefix": "rel/"}, {"kind": "feature", "enabled": true, "prefix": "feature/"}]
+			}`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client, closeServer := testClient(t, testResponses(testRepositoryResponses(map[string]string{
+				"/2.0/repositories/test-owner/test-repo":                            `{"name": "test-repo", "slug": "test-repo", "project": {"key": "PROJ"}}`,
+				"/2.0/repositories/test-owner/test-repo/branching-model/settings":   repoSettings,
+				"/2.0/workspaces/test-owner/projects/PROJ/branching-model/settings": tc.ProjectSettings,
+			})))
+			defer closeServer()
+
+			d := resourceRepository().Data(&terraform.InstanceState{
+				ID: "test-owner/test-repo",
+				Attributes: map[string]string{
+					"owner":                      "test-owner",
+					"name":                       "test-repo",
+					"branching_model_settings.#": "1",
+					"branching_model_settings.0.development.#":                "1",
+					"branching_model_settings.0.development.0.use_mainbranch": "true",
+				},
+			})
+
+			if err := resourceRepositoryRead(d, client); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if matches := d.Get("branching_model_matches_project").(bool); matches != tc.Matches {
+				t.Fatalf("expected branching_model_matches_project %t, got %t", tc.Matches, matches)
+			}
+		})
+	}
+}
+
+func TestRepositoryBranchingModel_defaultPrefixes(t *testing.T) {
+	settings := &BranchingModel{
+		Development: &BranchingModelBranch{UseMainbranch: true},
+		BranchTypes: []BranchType{
+			{Kind: "feature", Enabled: false, Prefix: "old-feature/"},
+			{Kind: "release", Enabled: false, Prefix: "old-release/"},
+		},
+	}
+
+	client, closeServer := testClient(t, testBranchingModelServer(settings))
+	defer closeServer()
+
+	raw := map[string]interface{}{
+		"owner": "test-owner",
+		"name":  "test-repo",
+		"branching_model_settings": []interface{}{
+			map[string]interface{}{
+				"development": []interface{}{
+					map[string]interface{}{"use_mainbranch": true},
+				},
+				"default_prefixes": map[string]interface{}{"release": "rel/"},
+				"branch_types": []interface{}{
+					map[string]interface{}{"kind": "feature"},
+					map[string]interface{}{"kind": "release"},
+				},
+			},
+		},
+	}
+
+	r := resourceRepository()
+	state := &terraform.InstanceState{
+		ID: "test-owner/test-repo",
+		Attributes: map[string]string{
+			"owner":             "test-owner",
+			"name":              "test-repo",
+			"slug":              "test-repo",
+			"scm":               "git",
+			"fork_policy":       "allow_forks",
+			"is_private":        "true",
+			"has_wiki":          "false",
+			"has_issues":        "false",
+			"archived":          "false",
+			"pipelines_enabled": "false",
+		},
+	}
+
+	diff, err := r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err = r.Apply(state, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]string{"feature": "feature/", "release": "rel/"}
+	for _, branchType := range settings.BranchTypes {
+		if branchType.Prefix != expected[branchType.Kind] || !branchType.Enabled {
+			t.Fatalf("expected %s to be enabled with prefix %s, got %#v", branchType.Kind, expected[branchType.Kind], branchType)
+		}
+	}
+
+	state, err = r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected no diff, got %#v", diff.Attributes)
+	}
+}
+
+func TestRepositoryBranchingModel_removeProduction(t *testing.T) {
+	enabled := true
+	settings := &BranchingModel{
+		Development: &BranchingModelBranch{Name: "develop"},
+		Production:  &BranchingModelBranch{Enabled: &enabled, Name: "master"},
+	}
+
+	client, closeServer := testClient(t, testBranchingModelServer(settings))
+	defer closeServer()
+
+	raw := func(branches map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"owner":                    "test-owner",
+			"name":                     "test-repo",
+			"branching_model_settings": []interface{}{branches},
+		}
+	}
+
+	configured := raw(map[string]interface{}{
+		"development": []interface{}{map[string]interface{}{"name": "develop"}},
+		"production":  []interface{}{map[string]interface{}{"name": "master"}},
+	})
+	removed := raw(map[string]interface{}{
+		"branch_types": []interface{}{map[string]interface{}{"kind": "feature"}},
+	})
+
+	r := resourceRepository()
+	state := &terraform.InstanceState{
+		ID: "test-owner/test-repo",
+		Attributes: map[string]string{
+			"owner":             "test-owner",
+			"name":              "test-repo",
+			"slug":              "test-repo",
+			"scm":               "git",
+			"fork_policy":       "allow_forks",
+			"is_private":        "true",
+			"has_wiki":          "false",
+			"has_issues":        "false",
+			"archived":          "false",
+			"pipelines_enabled": "false",
+		},
+	}
+
+	var err error
+	for _, config := range []map[string]interface{}{configured, removed} {
+		state, err = r.Refresh(state, client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		diff, err := r.Diff(state, testResourceConfig(t, config), client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if state, err = r.Apply(state, diff, client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if state, err = r.Refresh(state, client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		diff, err = r.Diff(state, testResourceConfig(t, config), client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !diff.Empty() {
+			t.Fatalf("expected no diff after apply, got %#v", diff.Attributes)
+		}
+	}
+
+	// Leaving the branches out resets them instead of keeping what bitbucket had
+	if settings.Production.Enabled == nil || *settings.Production.Enabled {
+		t.Fatalf("expected the production branch to be disabled, got %#v", settings.Production)
+	}
+	if !settings.Development.UseMainbranch {
+		t.Fatalf("expected the development branch to use the main branch, got %#v", settings.Development)
+	}
+}
+
+func TestRepositoryBranchingModel_removeAllBranchTypes(t *testing.T) {
+	settings := &BranchingModel{
+		Development: &BranchingModelBranch{UseMainbranch: true},
+		BranchTypes: []BranchType{
+			{Kind: "bugfix", Enabled: false, Prefix: "bugfix/"},
+			{Kind: "feature", Enabled: false, Prefix: "feature/"},
+			{Kind: "release", Enabled: false, Prefix: "release/"},
+		},
+	}
+
+	var sent map[string]interface{}
+	server := testBranchingModelServer(settings)
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" && r.URL.Path == "/2.0/repositories/test-owner/test-repo/branching-model/settings" {
+			body, _ := ioutil.ReadAll(r.Body)
+			sent = nil
+			json.Unmarshal(body, &sent)
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		server(w, r)
+	}))
+	defer closeServer()
+
+	// Without any branch types the block is left empty
+	raw := func(branchTypes ...string) map[string]interface{} {
+		block := make(map[string]interface{})
+		for _, kind := range branchTypes {
+			types, _ := block["branch_types"].([]interface{})
+			block["branch_types"] = append(types, map[string]interface{}{"kind": kind})
+		}
+
+		return map[string]interface{}{
+			"owner":                    "test-owner",
+			"name":                     "test-repo",
+			"branching_model_settings": []interface{}{block},
+		}
+	}
+
+	r := resourceRepository()
+	state := &terraform.InstanceState{
+		ID: "test-owner/test-repo",
+		Attributes: map[string]string{
+			"owner":             "test-owner",
+			"name":              "test-repo",
+			"slug":              "test-repo",
+			"scm":               "git",
+			"fork_policy":       "allow_forks",
+			"is_private":        "true",
+			"has_wiki":          "false",
+			"has_issues":        "false",
+			"archived":          "false",
+			"pipelines_enabled": "false",
+		},
+	}
+
+	for i, config := range []map[string]interface{}{raw(), raw("feature", "release"), raw()} {
+		sent = nil
+
+		diff, err := r.Diff(state, testResourceConfig(t, config), client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if state, err = r.Apply(state, diff, client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if state, err = r.Refresh(state, client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if diff, err = r.Diff(state, testResourceConfig(t, config), client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !diff.Empty() {
+			t.Fatalf("expected no diff after apply, got %#v", diff.Attributes)
+		}
+
+		if sent == nil {
+			t.Fatal("expected the branching model settings to be sent")
+		}
+
+		// Nothing to enable or disable yet, but still an explicit empty list
+		if branchTypes, ok := sent["branch_types"].([]interface{}); i == 0 && (!ok || len(branchTypes) != 0) {
+			t.Fatalf("expected an empty branch_types list, got %#v", sent)
+		}
+	}
+
+	// Removing every branch type still sends them, disabled, so none are left behind
+	branchTypes, ok := sent["branch_types"].([]interface{})
+	if !ok || len(branchTypes) != 2 {
+		t.Fatalf("expected the removed branch types to be sent, got %#v", sent)
+	}
+	for _, branchType := range settings.BranchTypes {
+		if branchType.Enabled {
+			t.Fatalf("expected %s to be disabled, got %#v", branchType.Kind, branchType)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+func repositoryEnvironmentSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"uuid": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"name": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"environment_type": {
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringInSlice([]string{"Test", "Staging", "Production"}, false),
+				},
+				"rank": {
+					Type:     schema.TypeInt,
+					Computed: true,
+				},
+			},
+		},
+	}
+}
+
+func repositoryEnvironmentURL(owner, repoSlug, uuid string) string {
+	return fmt.Sprintf("repositories/%s/%s/environments/%s", owner, repoSlug, url.PathEscape(uuid))
+}
+
+// setRepositoryEnvironments reconciles the inline environments by name, an environment that keeps
+// its name and type keeps its uuid and deployment history while the others are replaced
+func setRepositoryEnvironments(d *schema.ResourceData, client *Client, repoSlug string) error {
+	owner := d.Get("owner").(string)
+	old, new := d.GetChange("environment")
+
+	existing := make(map[string]map[string]interface{})
+	for _, item := range old.([]interface{}) {
+		m := item.(map[string]interface{})
+		if m["uuid"].(string) != "" {
+			existing[m["name"].(string)] = m
+		}
+	}
+
+	environments := make([]map[string]interface{}, 0, len(new.([]interface{})))
+	kept := make(map[string]bool)
+
+	for _, item := range new.([]interface{}) {
+		m := item.(map[string]interface{})
+		name := m["name"].(string)
+		environmentType := m["environment_type"].(string)
+
+		if current, ok := existing[name]; ok && current["environment_type"].(string) == environmentType {
+			kept[current["uuid"].(string)] = true
+			environments = append(environments, current)
+			continue
+		}
+
+		// The type of an environment can't be changed, the old one is deleted below
+		created, err := createEnvironment(client, owner, repoSlug, name, environmentType)
+		if err != nil {
+			return err
+		}
+
+		environments = append(environments, map[string]interface{}{
+			"uuid":             created.UUID,
+			"name":             name,
+			"environment_type": environmentType,
+			"rank":             environmentTypeRanks[environmentType],
+		})
+	}
+
+	for _, current := range existing {
+		uuid := current["uuid"].(string)
+		if kept[uuid] {
+			continue
+		}
+
+		// Somebody else already removed it
+		if err := client.DeleteIgnoringNotFound(repositoryEnvironmentURL(owner, repoSlug, uuid)); err != nil {
+			return err
+		}
+	}
+
+	d.Set("environment", environments)
+
+	return nil
+}
+
+// readRepositoryEnvironments refreshes the inline environments we created, environments added
+// outside of terraform or with bitbucket_deployment are left alone
+func readRepositoryEnvironments(d *schema.ResourceData, client *Client, repoSlug string) error {
+	inState := d.Get("environment").([]interface{})
+	if len(inState) == 0 {
+		return nil
+	}
+
+	environments := make([]map[string]interface{}, 0, len(inState))
+
+	for _, item := range inState {
+		uuid := item.(map[string]interface{})["uuid"].(string)
+		if uuid == "" {
+			continue
+		}
+
+		environmentReq, err := client.Get(repositoryEnvironmentURL(d.Get("owner").(string), repoSlug, uuid))
+
+		// Removed outside of terraform, dropping it makes the plan create it again
+		if environmentReq != nil && environmentReq.StatusCode == 404 {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var environment Environment
+
+		decodeerr := json.NewDecoder(environmentReq.Body).Decode(&environment)
+		if decodeerr != nil {
+			return decodeerr
+		}
+
+		environments = append(environments, map[string]interface{}{
+			"uuid":             environment.UUID,
+			"name":             environment.Name,
+			"environment_type": environment.EnvironmentType.Name,
+			"rank":             environment.EnvironmentType.Rank,
+		})
+	}
+
+	d.Set("environment", environments)
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package bitbucket
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestRepositoryEnvironments_reconcile(t *testing.T) {
+	environments := &testEnvironmentsServer{environments: make(map[string]Environment)}
+	responses := testRepositoryResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo", "scm": "git", "fork_policy": "allow_forks", "is_private": true}`,
+	})
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/2.0/repositories/test-owner/test-repo/environments/") {
+			environments.ServeHTTP(w, r)
+			return
+		}
+		testResponses(responses)(w, r)
+	}))
+	defer closeServer()
+
+	raw := func(blocks ...string) map[string]interface{} {
+		var configured []interface{}
+		for _, block := range blocks {
+			parts := strings.Split(block, ":")
+			configured = append(configured, map[string]interface{}{
+				"name":             parts[0],
+				"environment_type": parts[1],
+			})
+		}
+
+		return map[string]interface{}{
+			"owner":       "test-owner",
+			"name":        "test-repo",
+			"environment": configured,
+		}
+	}
+
+	apply := func(state *terraform.InstanceState, config map[string]interface{}) *terraform.InstanceState {
+		r := resourceRepository()
+
+		state, err := r.Refresh(state, client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		diff, err := r.Diff(state, testResourceConfig(t, config), client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		state, err = r.Apply(state, diff, client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		state, err = r.Refresh(state, client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		diff, err = r.Diff(state, testResourceConfig(t, config), client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !diff.Empty() {
+			t.Fatalf("expected no diff after apply, got %#v", diff.Attributes)
+		}
+
+		return state
+	}
+
+	names := func() map[string]string {
+		byUUID := make(map[string]string)
+		for uuid, environment := range environments.environments {
+			byUUID[uuid] = environment.Name + ":" + environment.EnvironmentType.Name
+		}
+		return byUUID
+	}
+
+	state := apply(&terraform.InstanceState{
+		ID: "test-owner/test-repo",
+		Attributes: map[string]string{
+			"owner":             "test-owner",
+			"name":              "test-repo",
+			"slug":              "test-repo",
+			"scm":               "git",
+			"fork_policy":       "allow_forks",
+			"is_private":        "true",
+			"has_wiki":          "false",
+			"has_issues":        "false",
+			"archived":          "false",
+			"pipelines_enabled": "false",
+		},
+	}, raw("Test:Test", "Production:Production"))
+
+	if got := names(); len(got) != 2 || got["{environment-1}"] != "Test:Test" || got["{environment-2}"] != "Production:Production" {
+		t.Fatalf("expected both environments to be created, got %v", got)
+	}
+	if state.Attributes["environment.1.uuid"] != "{environment-2}" || state.Attributes["environment.1.rank"] != "2" {
+		t.Fatalf("unexpected state %#v", state.Attributes)
+	}
+
+	// Reordered, with one renamed and one added, only the untouched one keeps its uuid
+	state = apply(state, raw("Staging:Staging", "Live:Production", "Test:Test"))
+
+	got := names()
+	if len(got) != 3 || got["{environment-1}"] != "Test:Test" || got["{environment-3}"] != "Staging:Staging" ||
+		got["{environment-4}"] != "Live:Production" {
+		t.Fatalf("expected Test to be kept and Production replaced by Live, got %v", got)
+	}
+	if state.Attributes["environment.2.uuid"] != "{environment-1}" {
+		t.Fatalf("expected the environments to follow the configured order, got %#v", state.Attributes)
+	}
+
+	// The type can't be changed so the environment is replaced
+	state = apply(state, raw("Staging:Staging", "Live:Production", "Test:Staging"))
+
+	if got := names(); got["{environment-1}"] != "" || got["{environment-5}"] != "Test:Staging" {
+		t.Fatalf("expected Test to be replaced, got %v", got)
+	}
+
+	// Deleted outside of terraform, the next apply creates it again
+	delete(environments.environments, "{environment-3}")
+	state = apply(state, raw("Staging:Staging", "Live:Production", "Test:Staging"))
+
+	if got := names(); len(got) != 3 || got["{environment-6}"] != "Staging:Staging" {
+		t.Fatalf("expected Staging to be created again, got %v", got)
+	}
+
+	// Removing the blocks deletes the environments
+	apply(state, raw())
+
+	if got := names(); len(got) != 0 {
+		t.Fatalf("expected the environments to be deleted, got %v", got)
+	}
+}
@@ -0,0 +1,52 @@
+package bitbucket
+
+import (
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func repositoryIssueTrackerSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"component_count": {
+					Type:     schema.TypeInt,
+					Computed: true,
+				},
+				"version_count": {
+					Type:     schema.TypeInt,
+					Computed: true,
+				},
+				"milestone_count": {
+					Type:     schema.TypeInt,
+					Computed: true,
+				},
+			},
+		},
+	}
+}
+
+// readRepositoryIssueTracker summarizes how the issue tracker is set up when read_issue_tracker
+// asks for it, repositories without an issue tracker are left without a summary
+func readRepositoryIssueTracker(d *schema.ResourceData, client *Client, repoSlug string) error {
+	if !d.Get("read_issue_tracker").(bool) || !d.Get("has_issues").(bool) {
+		d.Set("issue_tracker", []map[string]interface{}{})
+		return nil
+	}
+
+	summary := make(map[string]interface{})
+
+	for _, kind := range []string{"component", "version", "milestone"} {
+		count, err := countRepositoryItems(client, d.Get("owner").(string), repoSlug, kind+"s")
+		if err != nil {
+			return err
+		}
+
+		summary[kind+"_count"] = count
+	}
+
+	d.Set("issue_tracker", []map[string]interface{}{summary})
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package bitbucket
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func TestRepositoryRead_issueTracker(t *testing.T) {
+	cases := map[string]struct {
+		HasIssues bool
+		Read      bool
+		Summary   map[string]interface{}
+	}{
+		"issues enabled": {
+			HasIssues: true,
+			Read:      true,
+			Summary: map[string]interface{}{
+				"component_count": 2,
+				"version_count":   0,
+				"milestone_count": 5,
+			},
+		},
+		"issues disabled": {
+			Read: true,
+		},
+		"not asked for": {
+			HasIssues: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			hasIssues := "false"
+			if tc.HasIssues {
+				hasIssues = "true"
+			}
+
+			responses := testRepositoryResponses(map[string]string{
+				"/2.0/repositories/test-owner/test-repo":            `{"name": "test-repo", "slug": "test-repo", "has_issues": ` + hasIssues + `}`,
+				"/2.0/repositories/test-owner/test-repo/components": `{"pagelen": 1, "size": 2, "values": [{"id": 1, "name": "api"}]}`,
+				"/2.0/repositories/test-owner/test-repo/versions":   `{"pagelen": 1, "size": 0, "values": []}`,
+				"/2.0/repositories/test-owner/test-repo/milestones": `{"pagelen": 1, "size": 5, "values": [{"id": 1, "name": "v1"}]}`,
+			})
+
+			var counted []string
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/2.0/repositories/test-owner/test-repo/components",
+					"/2.0/repositories/test-owner/test-repo/versions",
+					"/2.0/repositories/test-owner/test-repo/milestones":
+					if r.URL.Query().Get("pagelen") != "1" {
+						t.Errorf("expected just one item to be asked for, got %s", r.URL)
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					counted = append(counted, r.URL.Path)
+				}
+				testResponses(responses)(w, r)
+			}))
+			defer closeServer()
+
+			d := schema.TestResourceDataRaw(t, resourceRepository().Schema, map[string]interface{}{
+				"owner":              "test-owner",
+				"name":               "test-repo",
+				"read_issue_tracker": tc.Read,
+			})
+			d.SetId("test-owner/test-repo")
+
+			if err := resourceRepositoryRead(d, client); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			summaries := d.Get("issue_tracker").([]interface{})
+			if tc.Summary == nil {
+				if len(summaries) != 0 || len(counted) != 0 {
+					t.Fatalf("expected no summary to be read, got %v after %v", summaries, counted)
+				}
+				return
+			}
+
+			if len(summaries) != 1 {
+				t.Fatalf("expected a summary, got %v", summaries)
+			}
+			for key, want := range tc.Summary {
+				if got := summaries[0].(map[string]interface{})[key]; got != want {
+					t.Fatalf("expected %s %v, got %v", key, want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func repositoryWebhookSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"uuid": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"url": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"description": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"active": {
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  true,
+				},
+				"events": {
+					Type:     schema.TypeSet,
+					Required: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+					Set:      schema.HashString,
+				},
+				"skip_cert_verification": {
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  true,
+				},
+			},
+		},
+	}
+}
+
+func repositoryWebhookURL(owner, repoSlug, uuid string) string {
+	return fmt.Sprintf("repositories/%s/%s/hooks/%s", owner, repoSlug, url.PathEscape(uuid))
+}
+
+// setRepositoryWebhook creates, updates or deletes the inline webhook to match the config, the
+// webhook keeps its uuid when it is changed
+func setRepositoryWebhook(d *schema.ResourceData, client *Client, repoSlug string) error {
+	owner := d.Get("owner").(string)
+	old, new := d.GetChange("webhook")
+
+	var uuid string
+	if oldBlocks := old.([]interface{}); len(oldBlocks) > 0 && oldBlocks[0] != nil {
+		uuid = oldBlocks[0].(map[string]interface{})["uuid"].(string)
+	}
+
+	newBlocks := new.([]interface{})
+	if len(newBlocks) == 0 || newBlocks[0] == nil {
+		if uuid == "" {
+			return nil
+		}
+
+		// Somebody else already removed it
+		return client.DeleteIgnoringNotFound(repositoryWebhookURL(owner, repoSlug, uuid))
+	}
+
+	hook := expandWebhook(newBlocks[0].(map[string]interface{}))
+
+	payload, err := json.Marshal(hook)
+	if err != nil {
+		return err
+	}
+
+	if uuid != "" {
+		_, err = client.Put(repositoryWebhookURL(owner, repoSlug, uuid), bytes.NewBuffer(payload))
+		if err == nil {
+			hook.UUID = uuid
+			d.Set("webhook", flattenRepositoryWebhook(hook))
+			return nil
+		}
+
+		// Removed outside of terraform since the last refresh, it is created again below
+		if apiErr, ok := err.(Error); !ok || apiErr.StatusCode != 404 {
+			return err
+		}
+	}
+
+	hookReq, err := client.Post(fmt.Sprintf("repositories/%s/%s/hooks", owner, repoSlug), bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+
+	var created Hook
+
+	decodeerr := json.NewDecoder(hookReq.Body).Decode(&created)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	hook.UUID = created.UUID
+	d.Set("webhook", flattenRepositoryWebhook(hook))
+
+	return nil
+}
+
+// readRepositoryWebhook refreshes the inline webhook we created, only that one hook is tracked so
+// hooks managed with bitbucket_hook aren't picked up
+func readRepositoryWebhook(d *schema.ResourceData, client *Client, repoSlug string) error {
+	blocks := d.Get("webhook").([]interface{})
+	if len(blocks) == 0 || blocks[0] == nil {
+		return nil
+	}
+
+	uuid := blocks[0].(map[string]interface{})["uuid"].(string)
+	if uuid == "" {
+		return nil
+	}
+
+	hookReq, err := client.Get(repositoryWebhookURL(d.Get("owner").(string), repoSlug, uuid))
+
+	// Removed outside of terraform, dropping it makes the plan create it again
+	if hookReq != nil && hookReq.StatusCode == 404 {
+		d.Set("webhook", nil)
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var hook Hook
+
+	decodeerr := json.NewDecoder(hookReq.Body).Decode(&hook)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	d.Set("webhook", flattenRepositoryWebhook(hook))
+
+	return nil
+}
+
+func flattenRepositoryWebhook(hook Hook) []map[string]interface{} {
+	return []map[string]interface{}{{
+		"uuid":                   hook.UUID,
+		"url":                    hook.URL,
+		"description":            hook.Description,
+		"active":                 hook.Active,
+		"events":                 hook.Events,
+		"skip_cert_verification": hook.SkipCertVerification,
+	}}
+}
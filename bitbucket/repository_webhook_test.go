@@ -0,0 +1,155 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// testHooksServer serves a repository whose hooks can be created, updated and deleted
+type testHooksServer struct {
+	hooks   map[string]Hook
+	created int
+}
+
+func (s *testHooksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const hooksPath = "/2.0/repositories/test-owner/test-repo/hooks"
+
+	if !strings.HasPrefix(r.URL.Path, hooksPath) {
+		testResponses(testRepositoryResponses(map[string]string{
+			"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo", "scm": "git", "fork_policy": "allow_forks", "is_private": true}`,
+		}))(w, r)
+		return
+	}
+
+	uuid := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, hooksPath), "/")
+
+	var hook Hook
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&hook)
+	}
+
+	switch {
+	case r.Method == "POST" && uuid == "":
+		s.created++
+		hook.UUID = fmt.Sprintf("{hook-%d}", s.created)
+		s.hooks[hook.UUID] = hook
+	case r.Method == "PUT" && s.hooks[uuid].UUID != "":
+		hook.UUID = uuid
+		s.hooks[uuid] = hook
+	case r.Method == "DELETE" && s.hooks[uuid].UUID != "":
+		delete(s.hooks, uuid)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case r.Method == "GET" && s.hooks[uuid].UUID != "":
+		hook = s.hooks[uuid]
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(hook)
+}
+
+func TestRepositoryWebhook_lifecycle(t *testing.T) {
+	server := &testHooksServer{hooks: make(map[string]Hook)}
+	client, closeServer := testClient(t, server)
+	defer closeServer()
+
+	raw := func(url string) map[string]interface{} {
+		config := map[string]interface{}{
+			"owner": "test-owner",
+			"name":  "test-repo",
+		}
+		if url != "" {
+			config["webhook"] = []interface{}{
+				map[string]interface{}{
+					"url":         url,
+					"description": "Notify",
+					"events":      []interface{}{"repo:push", "pullrequest:created"},
+				},
+			}
+		}
+		return config
+	}
+
+	apply := func(state *terraform.InstanceState, config map[string]interface{}) *terraform.InstanceState {
+		r := resourceRepository()
+
+		state, err := r.Refresh(state, client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		diff, err := r.Diff(state, testResourceConfig(t, config), client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		state, err = r.Apply(state, diff, client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		state, err = r.Refresh(state, client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		diff, err = r.Diff(state, testResourceConfig(t, config), client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !diff.Empty() {
+			t.Fatalf("expected no diff after apply, got %#v", diff.Attributes)
+		}
+
+		return state
+	}
+
+	state := apply(&terraform.InstanceState{
+		ID: "test-owner/test-repo",
+		Attributes: map[string]string{
+			"owner":             "test-owner",
+			"name":              "test-repo",
+			"slug":              "test-repo",
+			"scm":               "git",
+			"fork_policy":       "allow_forks",
+			"is_private":        "true",
+			"has_wiki":          "false",
+			"has_issues":        "false",
+			"archived":          "false",
+			"pipelines_enabled": "false",
+		},
+	}, raw("https://example.com/one"))
+
+	if len(server.hooks) != 1 || server.hooks["{hook-1}"].URL != "https://example.com/one" {
+		t.Fatalf("expected the webhook to be created, got %v", server.hooks)
+	}
+
+	// Changing the webhook updates it in place
+	state = apply(state, raw("https://example.com/two"))
+
+	if server.created != 1 || server.hooks["{hook-1}"].URL != "https://example.com/two" {
+		t.Fatalf("expected the webhook to be updated, got %v", server.hooks)
+	}
+
+	// Deleted outside of terraform, the next apply creates it again
+	delete(server.hooks, "{hook-1}")
+	state = apply(state, raw("https://example.com/two"))
+
+	if _, ok := server.hooks["{hook-2}"]; !ok {
+		t.Fatalf("expected the webhook to be created again, got %v", server.hooks)
+	}
+
+	// Removing the block deletes the webhook
+	apply(state, raw(""))
+
+	if len(server.hooks) != 0 {
+		t.Fatalf("expected the webhook to be deleted, got %v", server.hooks)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"io/ioutil"
 	"log"
 	"net/url"
+	"sort"
+	"strings"
 )
 
 // BranchRestriction is the data we need to send to create a new branch restriction for the repository
@@ -21,6 +23,14 @@ type BranchRestriction struct {
 	Groups  []Group `json:"groups,omitempty"`
 }
 
+// PaginatedBranchRestrictions is a paginated list of branch restrictions that the bitbucket api returns
+type PaginatedBranchRestrictions struct {
+	Values []BranchRestriction `json:"values,omitempty"`
+	Page   int                 `json:"page,omitempty"`
+	Size   int                 `json:"size,omitempty"`
+	Next   string              `json:"next,omitempty"`
+}
+
 // User is just the user struct we want to use for BranchRestrictions
 type User struct {
 	Username string `json:"username,omitempty"`
@@ -32,13 +42,35 @@ type Group struct {
 	Owner User   `json:"owner,omitempty"`
 }
 
+// branchRestrictionKinds are the kinds of branch restriction bitbucket supports
+var branchRestrictionKinds = []string{
+	"require_tasks_to_be_completed",
+	"require_passing_builds_to_merge",
+	"force",
+	"require_all_dependencies_merged",
+	"push",
+	"require_approvals_to_merge",
+	"enforce_merge_checks",
+	"restrict_merges",
+	"reset_pullrequest_approvals_on_change",
+	"delete",
+}
+
+// branchRestrictionValueMinimums holds the kinds that take a value and the smallest value that
+// makes sense for them, every other kind ignores the value so it must be left unset
+var branchRestrictionValueMinimums = map[string]int{
+	"require_approvals_to_merge":      1,
+	"require_passing_builds_to_merge": 1,
+}
+
 func resourceBranchRestriction() *schema.Resource {
 	return &schema.Resource{
-		Create: resourceBranchRestrictionsCreate,
-		Read:   resourceBranchRestrictionsRead,
-		Update: resourceBranchRestrictionsUpdate,
-		Delete: resourceBranchRestrictionsDelete,
-		Exists: resourceBranchRestrictionsExists,
+		Create:        resourceBranchRestrictionsCreate,
+		Read:          resourceBranchRestrictionsRead,
+		Update:        resourceBranchRestrictionsUpdate,
+		Delete:        resourceBranchRestrictionsDelete,
+		Exists:        resourceBranchRestrictionsExists,
+		CustomizeDiff: resourceBranchRestrictionsCustomizeDiff,
 
 		Schema: map[string]*schema.Schema{
 			"owner": {
@@ -52,25 +84,14 @@ func resourceBranchRestriction() *schema.Resource {
 				ForceNew: true,
 			},
 			"kind": {
-				Type:     schema.TypeString,
-				Required: true,
-				ValidateFunc: validation.StringInSlice([]string{
-					"require_tasks_to_be_completed",
-					"require_passing_builds_to_merge",
-					"force",
-					"require_all_dependencies_merged",
-					"push",
-					"require_approvals_to_merge",
-					"enforce_merge_checks",
-					"restrict_merges",
-					"reset_pullrequest_approvals_on_change",
-					"delete",
-				},
-					false),
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice(branchRestrictionKinds, false),
 			},
 			"pattern": {
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
+				Computed: true,
 			},
 			"users": {
 				Type:     schema.TypeSet,
@@ -79,19 +100,8 @@ func resourceBranchRestriction() *schema.Resource {
 				Set:      schema.HashString,
 			},
 			"groups": {
-				Type: schema.TypeSet,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"owner": {
-							Type:     schema.TypeString,
-							Required: true,
-						},
-						"slug": {
-							Type:     schema.TypeString,
-							Required: true,
-						},
-					},
-				},
+				Type:     schema.TypeSet,
+				Elem:     branchRestrictionGroupResource(),
 				Optional: true,
 			},
 
@@ -103,41 +113,140 @@ func resourceBranchRestriction() *schema.Resource {
 	}
 }
 
+func resourceBranchRestrictionsCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	// An interpolated value reads as 0 until apply, it is checked once it is known
+	if !d.NewValueKnown("kind") || !d.NewValueKnown("value") {
+		return nil
+	}
+
+	return validateBranchRestrictionValue(d.Get("kind").(string), d.Get("value").(int))
+}
+
+func validateBranchRestrictionValue(kind string, value int) error {
+	if kind == "" {
+		// The kind isn't known until apply
+		return nil
+	}
+
+	if minimum, ok := branchRestrictionValueMinimums[kind]; ok {
+		if value < minimum {
+			return fmt.Errorf("value must be at least %d when kind is %s", minimum, kind)
+		}
+		return nil
+	}
+
+	if value != 0 {
+		kinds := make([]string, 0, len(branchRestrictionValueMinimums))
+		for k := range branchRestrictionValueMinimums {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+
+		return fmt.Errorf("value can't be set when kind is %s, it is only used by %s", kind, strings.Join(kinds, ", "))
+	}
+
+	return nil
+}
+
+func branchRestrictionGroupResource() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"owner": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"slug": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+		},
+	}
+}
+
 func createBranchRestriction(d *schema.ResourceData) *BranchRestriction {
+	return expandBranchRestriction(map[string]interface{}{
+		"kind":    d.Get("kind"),
+		"pattern": d.Get("pattern"),
+		"value":   d.Get("value"),
+		"users":   d.Get("users"),
+		"groups":  d.Get("groups"),
+	})
+}
+
+// expandBranchRestriction builds a branch restriction from either the resource or an inline block
+func expandBranchRestriction(m map[string]interface{}) *BranchRestriction {
 
-	users := make([]User, 0, len(d.Get("users").(*schema.Set).List()))
+	users := make([]User, 0, len(m["users"].(*schema.Set).List()))
 
-	for _, item := range d.Get("users").(*schema.Set).List() {
+	for _, item := range m["users"].(*schema.Set).List() {
 		users = append(users, User{Username: item.(string)})
 	}
 
-	groups := make([]Group, 0, len(d.Get("groups").(*schema.Set).List()))
+	groups := make([]Group, 0, len(m["groups"].(*schema.Set).List()))
 
-	for _, item := range d.Get("groups").(*schema.Set).List() {
-		m := item.(map[string]interface{})
-		groups = append(groups, Group{Owner: User{Username: m["owner"].(string)}, Slug: m["slug"].(string)})
+	for _, item := range m["groups"].(*schema.Set).List() {
+		group := item.(map[string]interface{})
+		groups = append(groups, Group{Owner: User{Username: group["owner"].(string)}, Slug: group["slug"].(string)})
 	}
 
 	return &BranchRestriction{
-		Kind:    d.Get("kind").(string),
-		Pattern: d.Get("pattern").(string),
-		Value:   d.Get("value").(int),
+		Kind:    m["kind"].(string),
+		Pattern: m["pattern"].(string),
+		Value:   m["value"].(int),
 		Users:   users,
 		Groups:  groups,
 	}
 }
 
+func flattenBranchRestrictionUsers(users []User) []string {
+	usernames := make([]string, 0, len(users))
+	for _, user := range users {
+		usernames = append(usernames, user.Username)
+	}
+	return usernames
+}
+
+func flattenBranchRestrictionGroups(groups []Group) []map[string]interface{} {
+	flattened := make([]map[string]interface{}, 0, len(groups))
+	for _, group := range groups {
+		flattened = append(flattened, map[string]interface{}{
+			"owner": group.Owner.Username,
+			"slug":  group.Slug,
+		})
+	}
+	return flattened
+}
+
+// resolveBranchRestrictionPattern falls back to the main branch of the repository when no pattern was given
+func resolveBranchRestrictionPattern(d *schema.ResourceData, client *Client, branchRestriction *BranchRestriction) error {
+	if branchRestriction.Pattern != "" {
+		return nil
+	}
+
+	mainBranch, err := getRepositoryMainBranch(client, d.Get("owner").(string), d.Get("repository").(string))
+	if err != nil {
+		return fmt.Errorf("pattern is empty and it can't default to the main branch: %s", err)
+	}
+
+	branchRestriction.Pattern = mainBranch
+	return nil
+}
+
 func resourceBranchRestrictionsCreate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*Client)
 	branchRestriction := createBranchRestriction(d)
 
+	if err := resolveBranchRestrictionPattern(d, client, branchRestriction); err != nil {
+		return err
+	}
+
 	bytedata, err := json.Marshal(branchRestriction)
 
 	if err != nil {
 		return err
 	}
 
-	branchRestrictionReq, err := client.Post(fmt.Sprintf("2.0/repositories/%s/%s/branch-restrictions",
+	branchRestrictionReq, err := client.Post(fmt.Sprintf("repositories/%s/%s/branch-restrictions",
 		d.Get("owner").(string),
 		d.Get("repository").(string),
 	), bytes.NewBuffer(bytedata))
@@ -151,26 +260,97 @@ func resourceBranchRestrictionsCreate(d *schema.ResourceData, m interface{}) err
 		return readerr
 	}
 
-	decodeerr := json.Unmarshal(body, &branchRestriction)
+	var created BranchRestriction
+
+	decodeerr := json.Unmarshal(body, &created)
 	if decodeerr != nil {
 		return decodeerr
 	}
 
-	d.SetId(string(fmt.Sprintf("%v", branchRestriction.ID)))
+	d.SetId(string(fmt.Sprintf("%v", created.ID)))
+
+	if err := resourceBranchRestrictionsRead(d, m); err != nil {
+		return err
+	}
+
+	// The restriction read back may be one that existed before, keeping it out of state stops the
+	// next apply from tainting and destroying it
+	if err := verifyBranchRestriction(d, branchRestriction); err != nil {
+		d.SetId("")
+		return err
+	}
+
+	return nil
+}
+
+// verifyBranchRestriction checks the restriction read back is the one that was sent, instead of
+// failing bitbucket sometimes keeps a conflicting restriction that already exists. Users and groups
+// aren't compared, bitbucket no longer reliably returns the usernames they are configured by.
+func verifyBranchRestriction(d *schema.ResourceData, want *BranchRestriction) error {
+	if d.Id() == "" {
+		return fmt.Errorf("Bitbucket didn't keep the %s branch restriction for %s in %s/%s, a conflicting "+
+			"restriction may already exist", want.Kind, want.Pattern, d.Get("owner").(string), d.Get("repository").(string))
+	}
+
+	if differences := branchRestrictionDifferences(want, createBranchRestriction(d)); len(differences) > 0 {
+		return fmt.Errorf("Bitbucket didn't apply branch restriction %s in %s/%s as configured, a conflicting "+
+			"restriction may already exist: %s", d.Id(), d.Get("owner").(string), d.Get("repository").(string),
+			strings.Join(differences, ", "))
+	}
+
+	return nil
+}
+
+func branchRestrictionDifferences(want, got *BranchRestriction) []string {
+	var differences []string
+
+	compare := func(field, want, got string) {
+		if want != got {
+			differences = append(differences, fmt.Sprintf("%s is %q instead of %q", field, got, want))
+		}
+	}
+
+	compare("kind", want.Kind, got.Kind)
+	compare("pattern", want.Pattern, got.Pattern)
+	compare("value", fmt.Sprint(want.Value), fmt.Sprint(got.Value))
+
+	return differences
+}
 
-	return resourceBranchRestrictionsRead(d, m)
+func branchRestrictionUsersString(users []User) string {
+	usernames := flattenBranchRestrictionUsers(users)
+	sort.Strings(usernames)
+	return strings.Join(usernames, ", ")
+}
+
+func branchRestrictionGroupsString(groups []Group) string {
+	slugs := make([]string, 0, len(groups))
+	for _, group := range groups {
+		slugs = append(slugs, group.Owner.Username+"/"+group.Slug)
+	}
+	sort.Strings(slugs)
+	return strings.Join(slugs, ", ")
 }
 
 func resourceBranchRestrictionsRead(d *schema.ResourceData, m interface{}) error {
 	client := m.(*Client)
 
-	branchRestrictionsReq, _ := client.Get(fmt.Sprintf("2.0/repositories/%s/%s/branch-restrictions/%s",
+	branchRestrictionsReq, err := client.Get(fmt.Sprintf("repositories/%s/%s/branch-restrictions/%s",
 		d.Get("owner").(string),
 		d.Get("repository").(string),
 		url.PathEscape(d.Id()),
 	))
 
-	log.Printf("ID: %s", url.PathEscape(d.Id()))
+	if branchRestrictionsReq != nil && branchRestrictionsReq.StatusCode == 404 {
+		log.Printf("[WARN] Branch restriction %s not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	// Still failing once the retries ran out
+	if err != nil {
+		return err
+	}
 
 	if branchRestrictionsReq.StatusCode == 200 {
 		var branchRestriction BranchRestriction
@@ -188,8 +368,8 @@ func resourceBranchRestrictionsRead(d *schema.ResourceData, m interface{}) error
 		d.Set("kind", branchRestriction.Kind)
 		d.Set("pattern", branchRestriction.Pattern)
 		d.Set("value", branchRestriction.Value)
-		d.Set("users", branchRestriction.Users)
-		d.Set("groups", branchRestriction.Groups)
+		d.Set("users", flattenBranchRestrictionUsers(branchRestriction.Users))
+		d.Set("groups", flattenBranchRestrictionGroups(branchRestriction.Groups))
 	}
 
 	return nil
@@ -198,12 +378,17 @@ func resourceBranchRestrictionsRead(d *schema.ResourceData, m interface{}) error
 func resourceBranchRestrictionsUpdate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*Client)
 	branchRestriction := createBranchRestriction(d)
+
+	if err := resolveBranchRestrictionPattern(d, client, branchRestriction); err != nil {
+		return err
+	}
+
 	payload, err := json.Marshal(branchRestriction)
 	if err != nil {
 		return err
 	}
 
-	_, err = client.Put(fmt.Sprintf("2.0/repositories/%s/%s/branch-restrictions/%s",
+	_, err = client.Put(fmt.Sprintf("repositories/%s/%s/branch-restrictions/%s",
 		d.Get("owner").(string),
 		d.Get("repository").(string),
 		url.PathEscape(d.Id()),
@@ -213,34 +398,37 @@ func resourceBranchRestrictionsUpdate(d *schema.ResourceData, m interface{}) err
 		return err
 	}
 
-	return resourceBranchRestrictionsRead(d, m)
+	if err := resourceBranchRestrictionsRead(d, m); err != nil {
+		return err
+	}
+
+	return verifyBranchRestriction(d, branchRestriction)
 }
 
 func resourceBranchRestrictionsDelete(d *schema.ResourceData, m interface{}) error {
 	client := m.(*Client)
-	_, err := client.Delete(fmt.Sprintf("2.0/repositories/%s/%s/branch-restrictions/%s",
+	return client.DeleteIgnoringNotFound(fmt.Sprintf("repositories/%s/%s/branch-restrictions/%s",
 		d.Get("owner").(string),
 		d.Get("repository").(string),
 		url.PathEscape(d.Id()),
 	))
-
-	return err
 }
 
 func resourceBranchRestrictionsExists(d *schema.ResourceData, m interface{}) (bool, error) {
 	client := m.(*Client)
 
 	if v := d.Id(); v != "" {
-		branchRestrictionsReq, err := client.Get(fmt.Sprintf("2.0/repositories/%s/%s/branch-restrictions/%s",
+		branchRestrictionsReq, err := client.Get(fmt.Sprintf("repositories/%s/%s/branch-restrictions/%s",
 			d.Get("owner").(string),
 			d.Get("repository").(string),
 			url.PathEscape(d.Id()),
 		))
-		if err != nil {
-			panic(err)
+		// Removed outside of terraform, the plan creates it again
+		if branchRestrictionsReq != nil && branchRestrictionsReq.StatusCode == 404 {
+			return false, nil
 		}
 
-		if branchRestrictionsReq.StatusCode != 200 {
+		if err != nil {
 			return false, err
 		}
 
@@ -1,11 +1,16 @@
 package bitbucket
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
+	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -73,3 +78,230 @@ func testAccCheckBitbucketBranchRestrictionExists(n string, branchRestriction *B
 		return nil
 	}
 }
+
+func TestValidateBranchRestrictionValue(t *testing.T) {
+	cases := []struct {
+		Kind  string
+		Value int
+		Valid bool
+	}{
+		{Kind: "require_approvals_to_merge", Value: 2, Valid: true},
+		{Kind: "require_approvals_to_merge", Value: 0, Valid: false},
+		{Kind: "require_passing_builds_to_merge", Value: 1, Valid: true},
+		{Kind: "require_passing_builds_to_merge", Value: 0, Valid: false},
+		{Kind: "require_tasks_to_be_completed", Value: 0, Valid: true},
+		{Kind: "require_tasks_to_be_completed", Value: 1, Valid: false},
+		{Kind: "push", Value: 0, Valid: true},
+		{Kind: "force", Value: 3, Valid: false},
+		{Kind: "delete", Value: 0, Valid: true},
+		{Kind: "", Value: 5, Valid: true},
+	}
+
+	for _, tc := range cases {
+		err := validateBranchRestrictionValue(tc.Kind, tc.Value)
+		if tc.Valid && err != nil {
+			t.Errorf("expected kind %q with value %d to be valid, got %s", tc.Kind, tc.Value, err)
+		}
+		if !tc.Valid && err == nil {
+			t.Errorf("expected kind %q with value %d to be invalid", tc.Kind, tc.Value)
+		}
+	}
+}
+
+func TestBranchRestrictionsCreate_defaultsPatternToMainBranch(t *testing.T) {
+	cases := map[string]struct {
+		Pattern  string
+		Expected string
+	}{
+		"empty pattern uses the main branch": {
+			Pattern:  "",
+			Expected: "main",
+		},
+		"explicit pattern is kept": {
+			Pattern:  "release/*",
+			Expected: "release/*",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var created BranchRestriction
+
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == "GET" && r.URL.Path == "/2.0/repositories/test-owner/test-repo":
+					w.Write([]byte(`{"slug": "test-repo", "mainbranch": {"name": "main", "type": "branch"}}`))
+				case r.Method == "POST" && r.URL.Path == "/2.0/repositories/test-owner/test-repo/branch-restrictions":
+					json.NewDecoder(r.Body).Decode(&created)
+					w.Write([]byte(`{"id": 7}`))
+				case r.Method == "GET" && r.URL.Path == "/2.0/repositories/test-owner/test-repo/branch-restrictions/7":
+					created.ID = 7
+					json.NewEncoder(w).Encode(created)
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			defer closeServer()
+
+			d := schema.TestResourceDataRaw(t, resourceBranchRestriction().Schema, map[string]interface{}{
+				"owner":      "test-owner",
+				"repository": "test-repo",
+				"kind":       "push",
+				"pattern":    tc.Pattern,
+			})
+
+			if err := resourceBranchRestrictionsCreate(d, client); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if created.Pattern != tc.Expected {
+				t.Fatalf("expected the restriction to be created for %q, got %q", tc.Expected, created.Pattern)
+			}
+			if v := d.Get("pattern").(string); v != tc.Expected {
+				t.Fatalf("expected pattern %q in state, got %q", tc.Expected, v)
+			}
+		})
+	}
+}
+
+func TestBranchRestrictionsCreate_conflictingRestrictionKept(t *testing.T) {
+	cases := map[string]struct {
+		Stored string
+		Error  string
+	}{
+		"applied": {
+			Stored: `{"id": 7, "kind": "push", "pattern": "master", "users": [{"username": "alice"}]}`,
+		},
+		"users read back without usernames": {
+			Stored: `{"id": 7, "kind": "push", "pattern": "master", "users": [{"uuid": "{alice}"}]}`,
+		},
+		"conflicting restriction kept": {
+			Stored: `{"id": 7, "kind": "push", "pattern": "main"}`,
+			Error:  `Bitbucket didn't apply branch restriction 7 in test-owner/test-repo as configured, a conflicting restriction may already exist: pattern is "main" instead of "master"`,
+		},
+		"restriction dropped": {
+			Error: "Bitbucket didn't keep the push branch restriction for master in test-owner/test-repo",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == "POST" && r.URL.Path == "/2.0/repositories/test-owner/test-repo/branch-restrictions":
+					w.Write([]byte(`{"id": 7}`))
+				case r.Method == "GET" && r.URL.Path == "/2.0/repositories/test-owner/test-repo/branch-restrictions/7" && tc.Stored != "":
+					w.Write([]byte(tc.Stored))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer closeServer()
+
+			d := schema.TestResourceDataRaw(t, resourceBranchRestriction().Schema, map[string]interface{}{
+				"owner":      "test-owner",
+				"repository": "test-repo",
+				"kind":       "push",
+				"pattern":    "master",
+				"users":      []interface{}{"alice"},
+			})
+
+			err := resourceBranchRestrictionsCreate(d, client)
+			if tc.Error == "" {
+				if err != nil {
+					t.Fatalf("err: %s", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.Error) {
+				t.Fatalf("expected error containing %q, got %v", tc.Error, err)
+			}
+			if d.Id() != "" {
+				t.Fatalf("expected the restriction to be kept out of state, got %s", d.Id())
+			}
+		})
+	}
+}
+
+func TestBranchRestrictionsExists(t *testing.T) {
+	cases := map[string]struct {
+		StatusCode int
+		Exists     bool
+		Error      bool
+	}{
+		"found": {
+			StatusCode: http.StatusOK,
+			Exists:     true,
+		},
+		"removed outside of terraform": {
+			StatusCode: http.StatusNotFound,
+		},
+		"still failing after the retries": {
+			StatusCode: http.StatusServiceUnavailable,
+			Error:      true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var requests int
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(tc.StatusCode)
+				w.Write([]byte(`{"id": 1, "kind": "push", "pattern": "main"}`))
+			}))
+			defer closeServer()
+			client.MaxRetries = 2
+
+			d := schema.TestResourceDataRaw(t, resourceBranchRestriction().Schema, map[string]interface{}{
+				"owner":      "test-owner",
+				"repository": "test-repo",
+				"kind":       "push",
+				"pattern":    "main",
+			})
+			d.SetId("1")
+
+			exists, err := resourceBranchRestrictionsExists(d, client)
+			if tc.Error {
+				if err == nil || exists {
+					t.Fatalf("expected an error, got %t, %v", exists, err)
+				}
+				if requests != 3 {
+					t.Fatalf("expected the request to be retried, got %d requests", requests)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if exists != tc.Exists {
+				t.Fatalf("expected exists to be %t", tc.Exists)
+			}
+		})
+	}
+}
+
+func TestBranchRestrictions_unknownValueIsCheckedAtApply(t *testing.T) {
+	raw := map[string]interface{}{
+		"owner":      "test-owner",
+		"repository": "test-repo",
+		"kind":       "require_approvals_to_merge",
+		"pattern":    "main",
+		"value":      config.UnknownVariableValue,
+	}
+
+	diff, err := resourceBranchRestriction().Diff(nil, testResourceConfig(t, raw), nil)
+	if err != nil {
+		t.Fatalf("expected an unknown value not to fail the plan, got %s", err)
+	}
+	if !diff.Attributes["value"].NewComputed {
+		t.Fatalf("expected the value to be computed, got %#v", diff.Attributes["value"])
+	}
+
+	raw["value"] = 0
+	if _, err := resourceBranchRestriction().Diff(nil, testResourceConfig(t, raw), nil); err == nil {
+		t.Fatal("expected a known value of 0 to fail the plan")
+	}
+}
@@ -0,0 +1,194 @@
+package bitbucket
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// Bitbucket cloud has no api to scope default reviewers to paths, code owners are read from a
+// CODEOWNERS file committed to the repository instead so this resource manages that file.
+func resourceCodeowners() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceCodeownersCreate,
+		Read:   resourceCodeownersRead,
+		Update: resourceCodeownersUpdate,
+		Delete: resourceCodeownersDelete,
+
+		Schema: map[string]*schema.Schema{
+			"owner": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"repository": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"branch": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			"path": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  ".bitbucket/CODEOWNERS",
+				ForceNew: true,
+			},
+			"content": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"commit_message": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "Update CODEOWNERS",
+			},
+		},
+	}
+}
+
+// commitFiles commits to a branch through the src endpoint, files maps the paths to write to their
+// content and deleted lists the paths to remove
+func commitFiles(client *Client, owner, repoSlug, branch, message string, files map[string]string, deleted []string) error {
+	values := url.Values{}
+	values.Set("branch", branch)
+	values.Set("message", message)
+
+	for path, content := range files {
+		values.Set(path, content)
+	}
+
+	for _, path := range deleted {
+		values.Add("files", path)
+	}
+
+	_, err := client.PostForm(fmt.Sprintf("repositories/%s/%s/src", owner, repoSlug), values)
+
+	return err
+}
+
+// getFileContent returns the raw content of a file on a branch, ok is false when there is no such file
+func getFileContent(client *Client, owner, repoSlug, branch, path string) (content string, ok bool, err error) {
+	fileReq, err := client.Get(fmt.Sprintf("repositories/%s/%s/src/%s/%s",
+		owner,
+		repoSlug,
+		url.PathEscape(branch),
+		strings.TrimPrefix(path, "/"),
+	))
+
+	if fileReq != nil && fileReq.StatusCode == 404 {
+		return "", false, nil
+	}
+
+	if err != nil {
+		return "", false, err
+	}
+
+	body, err := ioutil.ReadAll(fileReq.Body)
+	if err != nil {
+		return "", false, err
+	}
+
+	return string(body), true, nil
+}
+
+func writeCodeowners(d *schema.ResourceData, client *Client) error {
+	err := commitFiles(client,
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+		d.Get("branch").(string),
+		d.Get("commit_message").(string),
+		map[string]string{d.Get("path").(string): d.Get("content").(string)},
+		nil,
+	)
+
+	if err != nil {
+		return fmt.Errorf("Failed to commit %s: %s", d.Get("path").(string), err)
+	}
+
+	return nil
+}
+
+func resourceCodeownersCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	if d.Get("branch").(string) == "" {
+		mainBranch, err := getRepositoryMainBranch(client, d.Get("owner").(string), d.Get("repository").(string))
+		if err != nil {
+			return err
+		}
+		d.Set("branch", mainBranch)
+	}
+
+	if err := writeCodeowners(d, client); err != nil {
+		return err
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", d.Get("owner").(string), d.Get("repository").(string)))
+
+	return resourceCodeownersRead(d, m)
+}
+
+func resourceCodeownersRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	content, ok, err := getFileContent(client,
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+		d.Get("branch").(string),
+		d.Get("path").(string),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	// The file was removed outside of terraform, so it is committed again
+	if !ok {
+		log.Printf("[WARN] %s not found on %s, removing from state", d.Get("path").(string), d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	d.Set("content", content)
+
+	return nil
+}
+
+func resourceCodeownersUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	if err := writeCodeowners(d, client); err != nil {
+		return err
+	}
+
+	return resourceCodeownersRead(d, m)
+}
+
+func resourceCodeownersDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	err := commitFiles(client,
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+		d.Get("branch").(string),
+		fmt.Sprintf("Remove %s", d.Get("path").(string)),
+		nil,
+		[]string{d.Get("path").(string)},
+	)
+
+	// The repository is gone and the file with it
+	if apiErr, ok := err.(Error); ok && apiErr.StatusCode == 404 {
+		return nil
+	}
+
+	return err
+}
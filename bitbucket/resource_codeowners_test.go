@@ -0,0 +1,144 @@
+package bitbucket
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// testSrcServer is a repository whose files can be committed through the src endpoint
+type testSrcServer struct {
+	files    map[string]string
+	branches []string
+	messages []string
+}
+
+func (s *testSrcServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/2.0/repositories/test-owner/test-repo":
+		w.Write([]byte(`{"name": "test-repo", "slug": "test-repo", "mainbranch": {"name": "main"}}`))
+	case r.Method == "POST" && r.URL.Path == "/2.0/repositories/test-owner/test-repo/src":
+		if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.ParseForm()
+
+		for key, values := range r.PostForm {
+			switch key {
+			case "branch":
+				s.branches = append(s.branches, values[0])
+			case "message":
+				s.messages = append(s.messages, values[0])
+			case "files":
+				for _, path := range values {
+					delete(s.files, path)
+				}
+			default:
+				s.files[key] = values[0]
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(r.URL.Path, "/2.0/repositories/test-owner/test-repo/src/main/"):
+		content, ok := s.files[strings.TrimPrefix(r.URL.Path, "/2.0/repositories/test-owner/test-repo/src/main/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(content))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestCodeowners_commitsFile(t *testing.T) {
+	server := &testSrcServer{files: make(map[string]string)}
+	client, closeServer := testClient(t, server)
+	defer closeServer()
+
+	raw := map[string]interface{}{
+		"owner":      "test-owner",
+		"repository": "test-repo",
+		"content":    "src/** @infra\n",
+	}
+
+	r := resourceCodeowners()
+	diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if server.files[".bitbucket/CODEOWNERS"] != "src/** @infra\n" {
+		t.Fatalf("expected CODEOWNERS to be committed, got %v", server.files)
+	}
+	if len(server.branches) != 1 || server.branches[0] != "main" {
+		t.Fatalf("expected a commit to the main branch, got %v", server.branches)
+	}
+	if state.Attributes["branch"] != "main" {
+		t.Fatalf("expected branch to default to the main branch, got %q", state.Attributes["branch"])
+	}
+
+	raw["content"] = "src/** @infra\ndocs/** @writers\n"
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff.RequiresNew() {
+		t.Fatal("expected a content change to be an update in place")
+	}
+
+	state, err = r.Apply(state, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if server.files[".bitbucket/CODEOWNERS"] != raw["content"] {
+		t.Fatalf("expected the new content to be committed, got %q", server.files[".bitbucket/CODEOWNERS"])
+	}
+
+	// Changed outside of terraform
+	server.files[".bitbucket/CODEOWNERS"] = "* @someone\n"
+	state, err = r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff.Empty() {
+		t.Fatal("expected the changed file to show as drift")
+	}
+
+	if _, err = r.Apply(state, &terraform.InstanceDiff{Destroy: true}, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := server.files[".bitbucket/CODEOWNERS"]; ok {
+		t.Fatal("expected CODEOWNERS to be removed")
+	}
+}
+
+func TestCodeowners_removedOutsideOfTerraform(t *testing.T) {
+	client, closeServer := testClient(t, &testSrcServer{files: make(map[string]string)})
+	defer closeServer()
+
+	d := resourceCodeowners().Data(nil)
+	d.SetId("test-owner/test-repo")
+	d.Set("owner", "test-owner")
+	d.Set("repository", "test-repo")
+	d.Set("branch", "main")
+	d.Set("path", ".bitbucket/CODEOWNERS")
+
+	if err := resourceCodeownersRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.Id() != "" {
+		t.Fatalf("expected the file to be removed from state, got %s", d.Id())
+	}
+}
@@ -0,0 +1,148 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceDefaultReviewer() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceDefaultReviewerCreate,
+		Read:   resourceDefaultReviewerRead,
+		Delete: resourceDefaultReviewerDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"owner": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"repository": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"username": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"email"},
+			},
+			"email": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"username"},
+			},
+			"uuid": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"display_name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func defaultReviewerURL(owner, repository, username string) string {
+	return fmt.Sprintf("repositories/%s/%s/default-reviewers/%s",
+		owner,
+		repository,
+		url.PathEscape(username),
+	)
+}
+
+func resourceDefaultReviewerCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	// A reviewer given by email is added and tracked by their uuid
+	user := d.Get("username").(string)
+	if user == "" {
+		if d.Get("email").(string) == "" {
+			return fmt.Errorf("One of username or email must be set")
+		}
+
+		reviewer, err := resolveUserByEmail(client, d.Get("owner").(string), d.Get("email").(string))
+		if err != nil {
+			return err
+		}
+		user = reviewer.UUID
+	}
+
+	_, err := client.PutOnly(defaultReviewerURL(
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+		user,
+	))
+
+	if err != nil {
+		return fmt.Errorf("Failed to add default reviewer %s: %s", user, err)
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s/%s",
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+		user,
+	))
+
+	return resourceDefaultReviewerRead(d, m)
+}
+
+func resourceDefaultReviewerRead(d *schema.ResourceData, m interface{}) error {
+	idparts := strings.SplitN(d.Id(), "/", 3)
+	if len(idparts) != 3 {
+		return fmt.Errorf("Incorrect ID format, should match `owner/repository/username`")
+	}
+
+	d.Set("owner", idparts[0])
+	d.Set("repository", idparts[1])
+	if d.Get("email").(string) == "" {
+		d.Set("username", idparts[2])
+	}
+
+	client := m.(*Client)
+	reviewerReq, err := client.Get(defaultReviewerURL(idparts[0], idparts[1], idparts[2]))
+
+	// The reviewer was removed outside of terraform, so it is added again
+	if reviewerReq != nil && reviewerReq.StatusCode == 404 {
+		log.Printf("[WARN] Default reviewer %s not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var reviewer Reviewer
+
+	decodeerr := json.NewDecoder(reviewerReq.Body).Decode(&reviewer)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	d.Set("uuid", reviewer.UUID)
+	d.Set("display_name", reviewer.DisplayName)
+
+	return nil
+}
+
+func resourceDefaultReviewerDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+	idparts := strings.SplitN(d.Id(), "/", 3)
+	return client.DeleteIgnoringNotFound(defaultReviewerURL(
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+		idparts[len(idparts)-1],
+	))
+}
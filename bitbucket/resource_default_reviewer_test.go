@@ -0,0 +1,126 @@
+package bitbucket
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestDefaultReviewer_create(t *testing.T) {
+	var put string
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			put = r.URL.Path
+		}
+		w.Write([]byte(`{"uuid": "{reviewer}", "display_name": "Reviewer"}`))
+	}))
+	defer closeServer()
+
+	r := resourceDefaultReviewer()
+	diff, err := r.Diff(nil, testResourceConfig(t, map[string]interface{}{
+		"owner":      "test-owner",
+		"repository": "test-repo",
+		"username":   "reviewer",
+	}), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if put != "/2.0/repositories/test-owner/test-repo/default-reviewers/reviewer" {
+		t.Fatalf("expected the reviewer to be PUT, got %q", put)
+	}
+	if state.ID != "test-owner/test-repo/reviewer" || state.Attributes["uuid"] != "{reviewer}" {
+		t.Fatalf("unexpected state %#v", state)
+	}
+}
+
+func TestDefaultReviewer_createByEmail(t *testing.T) {
+	var put string
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/2.0/workspaces/test-owner/members" {
+			w.Write([]byte(`{"values": [{"user": {"uuid": "{reviewer}"}}]}`))
+			return
+		}
+		if r.Method == "PUT" {
+			put = r.URL.Path
+		}
+		w.Write([]byte(`{"uuid": "{reviewer}", "display_name": "Reviewer"}`))
+	}))
+	defer closeServer()
+
+	r := resourceDefaultReviewer()
+	raw := map[string]interface{}{
+		"owner":      "test-owner",
+		"repository": "test-repo",
+		"email":      "reviewer@example.com",
+	}
+
+	diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if put != "/2.0/repositories/test-owner/test-repo/default-reviewers/{reviewer}" {
+		t.Fatalf("expected the reviewer to be PUT by uuid, got %q", put)
+	}
+	if state.ID != "test-owner/test-repo/{reviewer}" || state.Attributes["username"] != "" {
+		t.Fatalf("unexpected state %#v", state)
+	}
+
+	state, err = r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff, _ := r.Diff(state, testResourceConfig(t, raw), client); diff != nil && !diff.Empty() {
+		t.Fatalf("expected no changes, got %#v", diff)
+	}
+}
+
+func TestDefaultReviewer_import(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo/default-reviewers/reviewer": `{"uuid": "{reviewer}", "display_name": "Reviewer"}`,
+	}))
+	defer closeServer()
+
+	d := resourceDefaultReviewer().Data(&terraform.InstanceState{ID: "test-owner/test-repo/reviewer"})
+	if err := resourceDefaultReviewerRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for key, expected := range map[string]string{
+		"owner":        "test-owner",
+		"repository":   "test-repo",
+		"username":     "reviewer",
+		"display_name": "Reviewer",
+	} {
+		if v := d.Get(key).(string); v != expected {
+			t.Fatalf("expected %s to be %q, got %q", key, expected, v)
+		}
+	}
+}
+
+func TestDefaultReviewer_removedOutsideOfTerraform(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(nil))
+	defer closeServer()
+
+	d := resourceDefaultReviewer().Data(&terraform.InstanceState{ID: "test-owner/test-repo/reviewer"})
+	if err := resourceDefaultReviewerRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.Id() != "" {
+		t.Fatalf("expected the reviewer to be removed from state, got %s", d.Id())
+	}
+}
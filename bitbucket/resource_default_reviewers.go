@@ -26,6 +26,7 @@ func resourceDefaultReviewers() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceDefaultReviewersCreate,
 		Read:   resourceDefaultReviewersRead,
+		Update: resourceDefaultReviewersUpdate,
 		Delete: resourceDefaultReviewersDelete,
 
 		Schema: map[string]*schema.Schema{
@@ -42,35 +43,175 @@ func resourceDefaultReviewers() *schema.Resource {
 			"reviewers": {
 				Type:     schema.TypeSet,
 				Elem:     &schema.Schema{Type: schema.TypeString},
-				Required: true,
+				Optional: true,
+				Set:      schema.HashString,
+			},
+			"groups": {
+				Type:     schema.TypeSet,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Optional: true,
+				Set:      schema.HashString,
+			},
+			"group_members": {
+				Type:     schema.TypeSet,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Computed: true,
+				Set:      schema.HashString,
+			},
+			"emails": {
+				Type:     schema.TypeSet,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Optional: true,
+				Set:      schema.HashString,
+			},
+			"email_members": {
+				Type:     schema.TypeSet,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Computed: true,
 				Set:      schema.HashString,
-				ForceNew: true,
 			},
 		},
 	}
 }
 
+func stringSet(v interface{}) map[string]bool {
+	set := make(map[string]bool)
+	for _, item := range v.(*schema.Set).List() {
+		set[item.(string)] = true
+	}
+	return set
+}
+
+func stringSetList(set map[string]bool) []string {
+	list := make([]string, 0, len(set))
+	for item := range set {
+		list = append(list, item)
+	}
+	return list
+}
+
+// getGroupMembers returns the uuids of everybody in the given groups, groups only exist on the 1.0 api
+func getGroupMembers(client *Client, owner string, groups map[string]bool) (map[string]bool, error) {
+	members := make(map[string]bool)
+
+	for group := range groups {
+		groupMembers, err := listGroupMembers(client, owner, group)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, member := range groupMembers {
+			members[member.UUID] = true
+		}
+	}
+
+	return members, nil
+}
+
+// getEmailMembers returns the uuids of the workspace members with the given emails
+func getEmailMembers(client *Client, owner string, emails map[string]bool) (map[string]bool, error) {
+	members := make(map[string]bool)
+
+	for email := range emails {
+		user, err := resolveUserByEmail(client, owner, email)
+		if err != nil {
+			return nil, err
+		}
+
+		members[user.UUID] = true
+	}
+
+	return members, nil
+}
+
+func listGroupMembers(client *Client, owner, group string) ([]Reviewer, error) {
+	membersReq, err := client.Get(fmt.Sprintf("1.0/groups/%s/%s/members",
+		owner,
+		group,
+	))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var groupMembers []Reviewer
+
+	err = json.NewDecoder(membersReq.Body).Decode(&groupMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupMembers, nil
+}
+
+func addDefaultReviewer(d *schema.ResourceData, client *Client, user string) error {
+	reviewerResp, err := client.PutOnly(fmt.Sprintf("repositories/%s/%s/default-reviewers/%s",
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+		user,
+	))
+
+	if err != nil {
+		return err
+	}
+
+	defer reviewerResp.Body.Close()
+
+	if reviewerResp.StatusCode != 200 {
+		return fmt.Errorf("Failed to create reviewer %s got code %d", user, reviewerResp.StatusCode)
+	}
+
+	return nil
+}
+
+func removeDefaultReviewer(d *schema.ResourceData, client *Client, user string) error {
+	err := client.DeleteIgnoringNotFound(fmt.Sprintf("repositories/%s/%s/default-reviewers/%s",
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+		user,
+	))
+
+	if err != nil {
+		return fmt.Errorf("Could not delete %s from default reviewer: %s", user, err)
+	}
+
+	return nil
+}
+
 func resourceDefaultReviewersCreate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*Client)
 
 	for _, user := range d.Get("reviewers").(*schema.Set).List() {
-		reviewerResp, err := client.PutOnly(fmt.Sprintf("2.0/repositories/%s/%s/default-reviewers/%s",
-			d.Get("owner").(string),
-			d.Get("repository").(string),
-			user,
-		))
-
-		if err != nil {
+		if err := addDefaultReviewer(d, client, user.(string)); err != nil {
 			return err
 		}
+	}
+
+	members, err := getGroupMembers(client, d.Get("owner").(string), stringSet(d.Get("groups")))
+	if err != nil {
+		return err
+	}
 
-		if reviewerResp.StatusCode != 200 {
-			return fmt.Errorf("Failed to create reviewer %s got code %d", user.(string), reviewerResp.StatusCode)
+	for member := range members {
+		if err := addDefaultReviewer(d, client, member); err != nil {
+			return err
 		}
+	}
 
-		defer reviewerResp.Body.Close()
+	emailMembers, err := getEmailMembers(client, d.Get("owner").(string), stringSet(d.Get("emails")))
+	if err != nil {
+		return err
 	}
 
+	for member := range emailMembers {
+		if err := addDefaultReviewer(d, client, member); err != nil {
+			return err
+		}
+	}
+
+	d.Set("group_members", stringSetList(members))
+	d.Set("email_members", stringSetList(emailMembers))
+
 	d.SetId(fmt.Sprintf("%s/%s/reviewers", d.Get("owner").(string), d.Get("repository").(string)))
 	return resourceDefaultReviewersRead(d, m)
 }
@@ -78,69 +219,152 @@ func resourceDefaultReviewersCreate(d *schema.ResourceData, m interface{}) error
 func resourceDefaultReviewersRead(d *schema.ResourceData, m interface{}) error {
 	client := m.(*Client)
 
-	resourceURL := fmt.Sprintf("2.0/repositories/%s/%s/default-reviewers",
+	values, err := client.GetPaged(fmt.Sprintf("repositories/%s/%s/default-reviewers",
 		d.Get("owner").(string),
 		d.Get("repository").(string),
-	)
+	))
+	if err != nil {
+		return err
+	}
 
-	var reviewers PaginatedReviewers
 	var terraformReviewers []string
 
-	for {
-		reviewersResponse, err := client.Get(resourceURL)
-		if err != nil {
-			return err
-		}
+	reviewersInState := stringSet(d.Get("reviewers"))
+	groupMembersInState := stringSet(d.Get("group_members"))
+	emailMembersInState := stringSet(d.Get("email_members"))
+	actualReviewers := make(map[string]bool)
 
-		decoder := json.NewDecoder(reviewersResponse.Body)
-		err = decoder.Decode(&reviewers)
-		if err != nil {
+	for _, value := range values {
+		var reviewer Reviewer
+		if err := json.Unmarshal(value, &reviewer); err != nil {
 			return err
 		}
 
-		for _, reviewer := range reviewers.Values {
-			terraformReviewers = append(terraformReviewers, reviewer.UUID)
+		actualReviewers[reviewer.UUID] = true
+
+		// Reviewers added because of a group or an email are tracked by group_members and
+		// email_members instead
+		if (groupMembersInState[reviewer.UUID] || emailMembersInState[reviewer.UUID]) && !reviewersInState[reviewer.UUID] {
+			continue
 		}
+		terraformReviewers = append(terraformReviewers, reviewer.UUID)
+	}
 
-		if reviewers.Next != "" {
-			nextPage := reviewers.Page + 1
-			resourceURL = fmt.Sprintf("2.0/repositories/%s/%s/default-reviewers?page=%d",
-				d.Get("owner").(string),
-				d.Get("repository").(string),
-				nextPage,
-			)
-			reviewers = PaginatedReviewers{}
-		} else {
+	d.Set("reviewers", terraformReviewers)
+
+	// A reviewer given by email was removed outside of terraform, forget the emails so the plan
+	// adds them back
+	for member := range emailMembersInState {
+		if !actualReviewers[member] {
+			d.Set("emails", []string{})
 			break
 		}
 	}
 
-	d.Set("reviewers", terraformReviewers)
+	groups := stringSet(d.Get("groups"))
+	if len(groups) == 0 && len(groupMembersInState) == 0 {
+		return nil
+	}
+
+	members, err := getGroupMembers(client, d.Get("owner").(string), groups)
+	if err != nil {
+		return err
+	}
+
+	inSync := len(members) == len(groupMembersInState)
+	for member := range members {
+		if !groupMembersInState[member] || !actualReviewers[member] {
+			inSync = false
+		}
+	}
+
+	// Membership changed since the last apply, forget the groups so the plan adds them back
+	// and the update brings the default reviewers in line with the current members.
+	if !inSync {
+		d.Set("groups", []string{})
+	}
 
 	return nil
 }
 
-func resourceDefaultReviewersDelete(d *schema.ResourceData, m interface{}) error {
+func resourceDefaultReviewersUpdate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*Client)
 
-	for _, user := range d.Get("reviewers").(*schema.Set).List() {
-		resp, err := client.Delete(fmt.Sprintf("2.0/repositories/%s/%s/default-reviewers/%s",
-			d.Get("owner").(string),
-			d.Get("repository").(string),
-			user.(string),
-		))
+	members, err := getGroupMembers(client, d.Get("owner").(string), stringSet(d.Get("groups")))
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
+	old, new := d.GetChange("reviewers")
+	oldReviewers, reviewers := stringSet(old), stringSet(new)
+
+	for user := range reviewers {
+		if oldReviewers[user] {
+			continue
+		}
+
+		if err := addDefaultReviewer(d, client, user); err != nil {
+			return err
+		}
+	}
+
+	for member := range members {
+		if err := addDefaultReviewer(d, client, member); err != nil {
+			return err
+		}
+	}
+
+	emailMembers, err := getEmailMembers(client, d.Get("owner").(string), stringSet(d.Get("emails")))
+	if err != nil {
+		return err
+	}
+
+	for member := range emailMembers {
+		if err := addDefaultReviewer(d, client, member); err != nil {
+			return err
+		}
+	}
+
+	// Everybody we added before who is neither a reviewer, a group member nor given by email anymore
+	previous := stringSet(d.Get("group_members"))
+	for user := range stringSet(d.Get("email_members")) {
+		previous[user] = true
+	}
+	for user := range oldReviewers {
+		previous[user] = true
+	}
+
+	for user := range previous {
+		if members[user] || emailMembers[user] || reviewers[user] {
+			continue
+		}
+
+		if err := removeDefaultReviewer(d, client, user); err != nil {
 			return err
 		}
+	}
+
+	d.Set("group_members", stringSetList(members))
+	d.Set("email_members", stringSetList(emailMembers))
 
-		if resp.StatusCode != 204 {
-			return fmt.Errorf("[%d] Could not delete %s from default reviewer",
-				resp.StatusCode,
-				user.(string),
-			)
+	return resourceDefaultReviewersRead(d, m)
+}
+
+func resourceDefaultReviewersDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	users := stringSet(d.Get("reviewers"))
+	for member := range stringSet(d.Get("group_members")) {
+		users[member] = true
+	}
+	for member := range stringSet(d.Get("email_members")) {
+		users[member] = true
+	}
+
+	for user := range users {
+		if err := removeDefaultReviewer(d, client, user); err != nil {
+			return err
 		}
-		defer resp.Body.Close()
 	}
 	return nil
 }
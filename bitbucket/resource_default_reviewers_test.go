@@ -1,8 +1,13 @@
 package bitbucket
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/resource"
@@ -68,3 +73,278 @@ func testAccCheckBitbucketDefaultReviewersExists(n string) resource.TestCheckFun
 		return nil
 	}
 }
+
+// testDefaultReviewersServer keeps default reviewers and group members in memory
+type testDefaultReviewersServer struct {
+	t            *testing.T
+	reviewers    map[string]bool
+	groupMembers map[string][]string
+	// emails maps the email of a workspace member to their uuid
+	emails map[string]string
+	// pageSize splits the default reviewers into pages when set
+	pageSize int
+}
+
+func (s *testDefaultReviewersServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const reviewersPath = "/2.0/repositories/test-owner/test-repo/default-reviewers"
+	const groupsPath = "/1.0/groups/test-owner/"
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == reviewersPath:
+		var uuids []string
+		for uuid := range s.reviewers {
+			uuids = append(uuids, uuid)
+		}
+		sort.Strings(uuids)
+
+		reviewers := PaginatedReviewers{Page: 1}
+		if s.pageSize > 0 {
+			if page, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil {
+				reviewers.Page = page
+			}
+
+			start := (reviewers.Page - 1) * s.pageSize
+			if start > len(uuids) {
+				start = len(uuids)
+			}
+			uuids = uuids[start:]
+
+			if len(uuids) > s.pageSize {
+				uuids = uuids[:s.pageSize]
+				reviewers.Next = fmt.Sprintf("https://api.bitbucket.org%s?page=%d", reviewersPath, reviewers.Page+1)
+			}
+		}
+
+		for _, uuid := range uuids {
+			reviewers.Values = append(reviewers.Values, Reviewer{UUID: uuid})
+		}
+		json.NewEncoder(w).Encode(reviewers)
+	case r.Method == "PUT" && strings.HasPrefix(r.URL.Path, reviewersPath+"/"):
+		s.reviewers[strings.TrimPrefix(r.URL.Path, reviewersPath+"/")] = true
+		w.Write([]byte(`{}`))
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, reviewersPath+"/"):
+		delete(s.reviewers, strings.TrimPrefix(r.URL.Path, reviewersPath+"/"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, groupsPath):
+		group := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, groupsPath), "/members")
+		members := []Reviewer{}
+		for _, uuid := range s.groupMembers[group] {
+			members = append(members, Reviewer{UUID: uuid})
+		}
+		json.NewEncoder(w).Encode(members)
+	case r.Method == "GET" && r.URL.Path == "/2.0/workspaces/test-owner/members":
+		email := strings.TrimSuffix(strings.TrimPrefix(r.URL.Query().Get("q"), `user.email="`), `"`)
+		members := paginatedWorkspaceMemberships{Values: []workspaceMembership{}}
+		if uuid, ok := s.emails[email]; ok {
+			members.Values = append(members.Values, workspaceMembership{User: apiUser{UUID: uuid}})
+		}
+		json.NewEncoder(w).Encode(members)
+	default:
+		s.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func TestDefaultReviewers_groupMembershipChanges(t *testing.T) {
+	server := &testDefaultReviewersServer{
+		t:         t,
+		reviewers: map[string]bool{},
+		groupMembers: map[string][]string{
+			"developers": {"{alice}", "{bob}"},
+		},
+	}
+
+	client, closeServer := testClient(t, server)
+	defer closeServer()
+
+	r := resourceDefaultReviewers()
+	raw := map[string]interface{}{
+		"owner":      "test-owner",
+		"repository": "test-repo",
+		"reviewers":  []interface{}{"{carol}"},
+		"groups":     []interface{}{"developers"},
+	}
+
+	apply := func(state *terraform.InstanceState) *terraform.InstanceState {
+		diff, err := r.Diff(state, testResourceConfig(t, raw), client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if diff == nil {
+			return state
+		}
+		state, err = r.Apply(state, diff, client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return state
+	}
+
+	assertReviewers := func(expected ...string) {
+		if len(server.reviewers) != len(expected) {
+			t.Fatalf("expected reviewers %v, got %v", expected, server.reviewers)
+		}
+		for _, uuid := range expected {
+			if !server.reviewers[uuid] {
+				t.Fatalf("expected reviewers %v, got %v", expected, server.reviewers)
+			}
+		}
+	}
+
+	state := apply(nil)
+	assertReviewers("{alice}", "{bob}", "{carol}")
+
+	// Nothing changed so there is nothing to do
+	state, err := r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff, _ := r.Diff(state, testResourceConfig(t, raw), client); diff != nil && !diff.Empty() {
+		t.Fatalf("expected no changes, got %#v", diff)
+	}
+
+	// Bob leaves and Dave joins the group
+	server.groupMembers["developers"] = []string{"{alice}", "{dave}"}
+
+	state, err = r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff, _ := r.Diff(state, testResourceConfig(t, raw), client); diff == nil || diff.RequiresNew() {
+		t.Fatalf("expected an in place update, got %#v", diff)
+	}
+	state = apply(state)
+	assertReviewers("{alice}", "{carol}", "{dave}")
+
+	if state.Attributes["reviewers.#"] != "1" {
+		t.Fatalf("expected only carol in reviewers, got %v", state.Attributes)
+	}
+}
+
+func TestDefaultReviewers_emails(t *testing.T) {
+	server := &testDefaultReviewersServer{
+		t:         t,
+		reviewers: map[string]bool{},
+		emails: map[string]string{
+			"alice@example.com": "{alice}",
+			"bob@example.com":   "{bob}",
+		},
+	}
+
+	client, closeServer := testClient(t, server)
+	defer closeServer()
+
+	r := resourceDefaultReviewers()
+	raw := map[string]interface{}{
+		"owner":      "test-owner",
+		"repository": "test-repo",
+		"reviewers":  []interface{}{"{carol}"},
+		"emails":     []interface{}{"alice@example.com", "bob@example.com"},
+	}
+
+	diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(server.reviewers) != 3 || !server.reviewers["{alice}"] || !server.reviewers["{bob}"] || !server.reviewers["{carol}"] {
+		t.Fatalf("expected alice, bob and carol to be reviewers, got %v", server.reviewers)
+	}
+	if state.Attributes["reviewers.#"] != "1" || state.Attributes["email_members.#"] != "2" {
+		t.Fatalf("expected only carol in reviewers and alice and bob in email_members, got %v", state.Attributes)
+	}
+
+	state, err = r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff, _ := r.Diff(state, testResourceConfig(t, raw), client); diff != nil && !diff.Empty() {
+		t.Fatalf("expected no changes, got %#v", diff)
+	}
+
+	// Bob is dropped from the config
+	raw["emails"] = []interface{}{"alice@example.com"}
+
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := r.Apply(state, diff, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(server.reviewers) != 2 || server.reviewers["{bob}"] {
+		t.Fatalf("expected bob to be removed, got %v", server.reviewers)
+	}
+}
+
+func TestDefaultReviewers_largePaginatedSet(t *testing.T) {
+	server := &testDefaultReviewersServer{
+		t:         t,
+		reviewers: map[string]bool{},
+		pageSize:  10,
+	}
+
+	client, closeServer := testClient(t, server)
+	defer closeServer()
+
+	reviewers := func(from, to int) []interface{} {
+		var uuids []interface{}
+		for i := from; i < to; i++ {
+			uuids = append(uuids, fmt.Sprintf("{user-%03d}", i))
+		}
+		return uuids
+	}
+
+	r := resourceDefaultReviewers()
+	raw := map[string]interface{}{
+		"owner":      "test-owner",
+		"repository": "test-repo",
+		"reviewers":  reviewers(0, 35),
+	}
+
+	diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(server.reviewers) != 35 || state.Attributes["reviewers.#"] != "35" {
+		t.Fatalf("expected 35 reviewers across 4 pages, got %d on the server and %s in state",
+			len(server.reviewers), state.Attributes["reviewers.#"])
+	}
+
+	state, err = r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff, _ := r.Diff(state, testResourceConfig(t, raw), client); diff != nil && !diff.Empty() {
+		t.Fatalf("expected no changes, got %#v", diff)
+	}
+
+	// Swapping a few reviewers only touches those, the rest are left alone
+	raw["reviewers"] = reviewers(5, 40)
+
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff.RequiresNew() {
+		t.Fatal("expected an in place update")
+	}
+
+	if _, err = r.Apply(state, diff, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(server.reviewers) != 35 || server.reviewers["{user-000}"] || !server.reviewers["{user-039}"] {
+		t.Fatalf("expected reviewers 5 to 39, got %v", server.reviewers)
+	}
+}
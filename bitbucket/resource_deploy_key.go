@@ -0,0 +1,192 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// DeployKey is a read only ssh key that is added to a repository
+type DeployKey struct {
+	ID      int    `json:"id,omitempty"`
+	Key     string `json:"key,omitempty"`
+	Label   string `json:"label,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+func resourceDeployKey() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceDeployKeyCreate,
+		Read:   resourceDeployKeyRead,
+		Update: resourceDeployKeyUpdate,
+		Delete: resourceDeployKeyDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"owner": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"repository": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"key": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSSHKeyDiff,
+			},
+			"label": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"key_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"comment": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+// normalizeSSHKey strips the comment and any extra whitespace from a public key, bitbucket
+// stores the comment separately so it never comes back as part of the key
+func normalizeSSHKey(key string) string {
+	fields := strings.Fields(key)
+	if len(fields) > 2 {
+		fields = fields[:2]
+	}
+	return strings.Join(fields, " ")
+}
+
+func suppressSSHKeyDiff(k, old, new string, d *schema.ResourceData) bool {
+	return normalizeSSHKey(old) == normalizeSSHKey(new)
+}
+
+func newDeployKeyFromResource(d *schema.ResourceData) *DeployKey {
+	return &DeployKey{
+		Key:   d.Get("key").(string),
+		Label: d.Get("label").(string),
+	}
+}
+
+func resourceDeployKeyCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+	deployKey := newDeployKeyFromResource(d)
+
+	bytedata, err := json.Marshal(deployKey)
+	if err != nil {
+		return err
+	}
+
+	deployKeyReq, err := client.Post(fmt.Sprintf("repositories/%s/%s/deploy-keys",
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+	), bytes.NewBuffer(bytedata))
+
+	if err != nil {
+		return err
+	}
+
+	body, readerr := ioutil.ReadAll(deployKeyReq.Body)
+	if readerr != nil {
+		return readerr
+	}
+
+	decodeerr := json.Unmarshal(body, &deployKey)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s/%d", d.Get("owner").(string), d.Get("repository").(string), deployKey.ID))
+
+	return resourceDeployKeyRead(d, m)
+}
+
+func resourceDeployKeyRead(d *schema.ResourceData, m interface{}) error {
+	idparts := strings.Split(d.Id(), "/")
+	if len(idparts) != 3 {
+		return fmt.Errorf("Incorrect ID format, should match `owner/repository/key_id`")
+	}
+
+	d.Set("owner", idparts[0])
+	d.Set("repository", idparts[1])
+	d.Set("key_id", idparts[2])
+
+	client := m.(*Client)
+	deployKeyReq, err := client.Get(fmt.Sprintf("repositories/%s/%s/deploy-keys/%s",
+		idparts[0],
+		idparts[1],
+		idparts[2],
+	))
+
+	if deployKeyReq != nil && deployKeyReq.StatusCode == 404 {
+		d.SetId("")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var deployKey DeployKey
+
+	body, readerr := ioutil.ReadAll(deployKeyReq.Body)
+	if readerr != nil {
+		return readerr
+	}
+
+	decodeerr := json.Unmarshal(body, &deployKey)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	d.Set("key", normalizeSSHKey(deployKey.Key))
+	d.Set("label", deployKey.Label)
+	d.Set("comment", deployKey.Comment)
+
+	return nil
+}
+
+func resourceDeployKeyUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+	deployKey := newDeployKeyFromResource(d)
+
+	bytedata, err := json.Marshal(deployKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Put(fmt.Sprintf("repositories/%s/%s/deploy-keys/%s",
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+		d.Get("key_id").(string),
+	), bytes.NewBuffer(bytedata))
+
+	if err != nil {
+		return err
+	}
+
+	return resourceDeployKeyRead(d, m)
+}
+
+func resourceDeployKeyDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+	return client.DeleteIgnoringNotFound(fmt.Sprintf("repositories/%s/%s/deploy-keys/%s",
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+		d.Get("key_id").(string),
+	))
+}
@@ -0,0 +1,140 @@
+package bitbucket
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+const (
+	testDeployKey        = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIO9cqhodz+Cct3/SA/Juc5AAHfD1XO7mrE6KFPvjpSie terraform@acceptance"
+	testDeployKeyRotated = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIH4jplo4IuzJ09gcy0kUT502egSMbEi8Vav32K3ZZUGd terraform@acceptance"
+)
+
+func TestAccBitbucketDeployKey_basic(t *testing.T) {
+	var firstID string
+
+	testUser := os.Getenv("BITBUCKET_USERNAME")
+	testAccBitbucketDeployKeyConfig := func(key string) string {
+		return fmt.Sprintf(`
+		resource "bitbucket_repository" "test_repo" {
+			owner = "%s"
+			name = "test-repo-for-deploy-key-test"
+		}
+		resource "bitbucket_deploy_key" "test_repo_deploy_key" {
+			owner = "%s"
+			repository = "${bitbucket_repository.test_repo.name}"
+			key = "%s"
+			label = "terraform acceptance"
+		}
+	`, testUser, testUser, key)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckBitbucketDeployKeyDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccBitbucketDeployKeyConfig(testDeployKey),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckBitbucketDeployKeyExists("bitbucket_deploy_key.test_repo_deploy_key", &firstID),
+				),
+			},
+			{
+				ResourceName:      "bitbucket_deploy_key.test_repo_deploy_key",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				Config: testAccBitbucketDeployKeyConfig(testDeployKeyRotated),
+				Check: func(s *terraform.State) error {
+					var rotatedID string
+					if err := testAccCheckBitbucketDeployKeyExists("bitbucket_deploy_key.test_repo_deploy_key", &rotatedID)(s); err != nil {
+						return err
+					}
+					if rotatedID == firstID {
+						return fmt.Errorf("Expected the deploy key to be replaced, still has ID %s", firstID)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestDeployKey_keyChangeForcesNew(t *testing.T) {
+	state := &terraform.InstanceState{
+		ID: "owner/repo/1",
+		Attributes: map[string]string{
+			"owner":      "owner",
+			"repository": "repo",
+			"key":        normalizeSSHKey(testDeployKey),
+			"key_id":     "1",
+		},
+	}
+
+	cases := map[string]struct {
+		Key         string
+		RequiresNew bool
+	}{
+		"same key with comment": {
+			Key:         testDeployKey,
+			RequiresNew: false,
+		},
+		"rotated key": {
+			Key:         testDeployKeyRotated,
+			RequiresNew: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			diff, err := resourceDeployKey().Diff(state, testResourceConfig(t, map[string]interface{}{
+				"owner":      "owner",
+				"repository": "repo",
+				"key":        tc.Key,
+			}), nil)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if diff.RequiresNew() != tc.RequiresNew {
+				t.Fatalf("expected RequiresNew to be %t, got diff %#v", tc.RequiresNew, diff)
+			}
+		})
+	}
+}
+
+func testAccCheckBitbucketDeployKeyDestroy(s *terraform.State) error {
+	client := testAccProvider.Meta().(*Client)
+	rs, ok := s.RootModule().Resources["bitbucket_deploy_key.test_repo_deploy_key"]
+	if !ok {
+		return fmt.Errorf("Not found %s", "bitbucket_deploy_key.test_repo_deploy_key")
+	}
+
+	response, _ := client.Get(fmt.Sprintf("2.0/repositories/%s/%s/deploy-keys/%s", rs.Primary.Attributes["owner"], rs.Primary.Attributes["repository"], rs.Primary.Attributes["key_id"]))
+
+	if response.StatusCode != 404 {
+		return fmt.Errorf("Deploy key still exists")
+	}
+
+	return nil
+}
+
+func testAccCheckBitbucketDeployKeyExists(n string, id *string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found %s", n)
+		}
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("No deploy key ID is set")
+		}
+		*id = rs.Primary.ID
+		return nil
+	}
+}
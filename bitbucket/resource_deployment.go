@@ -0,0 +1,169 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+func resourceDeployment() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceDeploymentCreate,
+		Read:   resourceDeploymentRead,
+		Update: resourceDeploymentUpdate,
+		Delete: resourceDeploymentDelete,
+
+		Schema: map[string]*schema.Schema{
+			"owner": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"repository": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"environment_type": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"Test", "Staging", "Production"}, false),
+			},
+			"rank": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"uuid": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func deploymentURL(d *schema.ResourceData) string {
+	return fmt.Sprintf("repositories/%s/%s/environments/%s",
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+		url.PathEscape(d.Id()),
+	)
+}
+
+// createEnvironment adds a deployment environment to a repository, bitbucket only takes the type
+// as an object and the rank orders it in the deployments screen
+func createEnvironment(client *Client, owner, repoSlug, name, environmentType string) (*Environment, error) {
+	payload, err := json.Marshal(&Environment{
+		Name: name,
+		EnvironmentType: EnvironmentType{
+			Name: environmentType,
+			Rank: environmentTypeRanks[environmentType],
+			Type: "deployment_environment_type",
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	environmentReq, err := client.Post(fmt.Sprintf("repositories/%s/%s/environments/",
+		owner,
+		repoSlug,
+	), bytes.NewBuffer(payload))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var environment Environment
+
+	decodeerr := json.NewDecoder(environmentReq.Body).Decode(&environment)
+	if decodeerr != nil {
+		return nil, decodeerr
+	}
+
+	return &environment, nil
+}
+
+func resourceDeploymentCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	environment, err := createEnvironment(client,
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+		d.Get("name").(string),
+		d.Get("environment_type").(string),
+	)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(environment.UUID)
+
+	return resourceDeploymentRead(d, m)
+}
+
+func resourceDeploymentRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	environmentReq, err := client.Get(deploymentURL(d))
+
+	// The environment was removed outside of terraform
+	if environmentReq != nil && environmentReq.StatusCode == 404 {
+		log.Printf("[WARN] Deployment environment %s not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var environment Environment
+
+	decodeerr := json.NewDecoder(environmentReq.Body).Decode(&environment)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	d.Set("uuid", environment.UUID)
+	d.Set("name", environment.Name)
+	d.Set("environment_type", environment.EnvironmentType.Name)
+	d.Set("rank", environment.EnvironmentType.Rank)
+
+	return nil
+}
+
+func resourceDeploymentUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	// Environments can't be PUT, renaming one is a change posted against it
+	payload, err := json.Marshal(map[string]interface{}{
+		"change": map[string]interface{}{
+			"name": d.Get("name").(string),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Post(deploymentURL(d)+"/changes/", bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+
+	return resourceDeploymentRead(d, m)
+}
+
+func resourceDeploymentDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+	return client.DeleteIgnoringNotFound(deploymentURL(d))
+}
@@ -0,0 +1,152 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// testEnvironmentsServer serves the deployment environments of a repository
+type testEnvironmentsServer struct {
+	environments map[string]Environment
+	created      map[string]interface{}
+	count        int
+}
+
+func (s *testEnvironmentsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const environmentsPath = "/2.0/repositories/test-owner/test-repo/environments/"
+
+	path := strings.TrimPrefix(r.URL.Path, environmentsPath)
+	uuid := strings.TrimSuffix(path, "/changes/")
+
+	var payload map[string]interface{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}
+
+	environment, exists := s.environments[uuid]
+
+	switch {
+	case r.Method == "POST" && r.URL.Path == environmentsPath:
+		s.created = payload
+		s.count++
+		environmentType := payload["environment_type"].(map[string]interface{})
+		environment = Environment{
+			UUID: fmt.Sprintf("{environment-%d}", s.count),
+			Name: payload["name"].(string),
+			EnvironmentType: EnvironmentType{
+				Name: environmentType["name"].(string),
+				Rank: int(environmentType["rank"].(float64)),
+			},
+		}
+		s.environments[environment.UUID] = environment
+	case r.Method == "POST" && strings.HasSuffix(path, "/changes/") && exists:
+		environment.Name = payload["change"].(map[string]interface{})["name"].(string)
+		s.environments[uuid] = environment
+		w.WriteHeader(http.StatusAccepted)
+		return
+	case r.Method == "GET" && exists:
+	case r.Method == "DELETE" && exists:
+		delete(s.environments, uuid)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(environment)
+}
+
+func TestDeployment_lifecycle(t *testing.T) {
+	server := &testEnvironmentsServer{environments: make(map[string]Environment)}
+	client, closeServer := testClient(t, server)
+	defer closeServer()
+
+	raw := map[string]interface{}{
+		"owner":            "test-owner",
+		"repository":       "test-repo",
+		"name":             "Live",
+		"environment_type": "Production",
+	}
+
+	r := resourceDeployment()
+	diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	environmentType, ok := server.created["environment_type"].(map[string]interface{})
+	if !ok || environmentType["name"] != "Production" || environmentType["rank"] != float64(2) {
+		t.Fatalf("expected the environment type to be sent as an object with its rank, got %v", server.created)
+	}
+	if state.ID != "{environment-1}" || state.Attributes["rank"] != "2" {
+		t.Fatalf("unexpected state %#v", state)
+	}
+
+	// Renaming updates the environment in place
+	raw["name"] = "Production"
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff.RequiresNew() {
+		t.Fatal("expected a rename not to replace the environment")
+	}
+
+	state, err = r.Apply(state, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if server.environments["{environment-1}"].Name != "Production" || state.Attributes["name"] != "Production" {
+		t.Fatalf("expected the environment to be renamed, got %v", server.environments)
+	}
+
+	// Changing the type replaces it
+	raw["environment_type"] = "Staging"
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.RequiresNew() {
+		t.Fatal("expected changing the type to replace the environment")
+	}
+
+	// Removed outside of terraform
+	delete(server.environments, "{environment-1}")
+	state, err = r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if state != nil {
+		t.Fatalf("expected the environment to be removed from state, got %#v", state)
+	}
+}
+
+func TestDeployment_destroy(t *testing.T) {
+	server := &testEnvironmentsServer{environments: map[string]Environment{
+		"{environment-1}": {UUID: "{environment-1}", Name: "Live"},
+	}}
+	client, closeServer := testClient(t, server)
+	defer closeServer()
+
+	_, err := resourceDeployment().Apply(&terraform.InstanceState{
+		ID:         "{environment-1}",
+		Attributes: map[string]string{"owner": "test-owner", "repository": "test-repo"},
+	}, &terraform.InstanceDiff{Destroy: true}, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(server.environments) != 0 {
+		t.Fatalf("expected the environment to be deleted, got %v", server.environments)
+	}
+}
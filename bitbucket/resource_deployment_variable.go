@@ -0,0 +1,173 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceDeploymentVariable() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceDeploymentVariableCreate,
+		Update: resourceDeploymentVariableUpdate,
+		Read:   resourceDeploymentVariableRead,
+		Delete: resourceDeploymentVariableDelete,
+
+		Schema: map[string]*schema.Schema{
+			"uuid": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"owner": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"repository": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"deployment": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"key": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"value": {
+				Type:      schema.TypeString,
+				Required:  true,
+				Sensitive: true,
+			},
+			"secured": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+		},
+	}
+}
+
+func deploymentVariablesURL(d *schema.ResourceData) string {
+	return fmt.Sprintf("repositories/%s/%s/deployments_config/environments/%s/variables",
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+		url.PathEscape(d.Get("deployment").(string)),
+	)
+}
+
+// deploymentVariableURL points at the variable in the ID, which is the environment uuid and the
+// variable uuid
+func deploymentVariableURL(d *schema.ResourceData) (string, error) {
+	idparts := strings.Split(d.Id(), "/")
+	if len(idparts) != 2 {
+		return "", fmt.Errorf("Incorrect ID format, should match `deployment/uuid`")
+	}
+
+	return fmt.Sprintf("%s/%s", deploymentVariablesURL(d), url.PathEscape(idparts[1])), nil
+}
+
+func resourceDeploymentVariableCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	bytedata, err := json.Marshal(newRepositoryVariableFromResource(d))
+	if err != nil {
+		return err
+	}
+
+	req, err := client.Post(deploymentVariablesURL(d), bytes.NewBuffer(bytedata))
+	if err != nil {
+		return err
+	}
+
+	var variable RepositoryVariable
+
+	decodeerr := json.NewDecoder(req.Body).Decode(&variable)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", d.Get("deployment").(string), variable.UUID))
+
+	return resourceDeploymentVariableRead(d, m)
+}
+
+func resourceDeploymentVariableRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	variableURL, err := deploymentVariableURL(d)
+	if err != nil {
+		return err
+	}
+
+	req, err := client.Get(variableURL)
+
+	// The variable or its environment was removed outside of terraform
+	if req != nil && req.StatusCode == 404 {
+		log.Printf("[WARN] Deployment variable %s not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var variable RepositoryVariable
+
+	decodeerr := json.NewDecoder(req.Body).Decode(&variable)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	d.Set("uuid", variable.UUID)
+	d.Set("key", variable.Key)
+	d.Set("secured", variable.Secured)
+
+	// Bitbucket never returns the value of a secured variable, the configured one is kept
+	if !variable.Secured {
+		d.Set("value", variable.Value)
+	}
+
+	return nil
+}
+
+func resourceDeploymentVariableUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	variableURL, err := deploymentVariableURL(d)
+	if err != nil {
+		return err
+	}
+
+	bytedata, err := json.Marshal(newRepositoryVariableFromResource(d))
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Put(variableURL, bytes.NewBuffer(bytedata))
+	if err != nil {
+		return err
+	}
+
+	return resourceDeploymentVariableRead(d, m)
+}
+
+func resourceDeploymentVariableDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	variableURL, err := deploymentVariableURL(d)
+	if err != nil {
+		return err
+	}
+
+	return client.DeleteIgnoringNotFound(variableURL)
+}
@@ -0,0 +1,104 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestDeploymentVariable_securedValueIsNotDrift(t *testing.T) {
+	var stored RepositoryVariable
+	var updated bool
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const variablesPath = "/2.0/repositories/test-owner/test-repo/deployments_config/environments/{environment}/variables"
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == variablesPath:
+			json.NewDecoder(r.Body).Decode(&stored)
+			stored.UUID = "{variable}"
+		case r.Method == "PUT" && r.URL.Path == variablesPath+"/{variable}":
+			json.NewDecoder(r.Body).Decode(&stored)
+			stored.UUID = "{variable}"
+			updated = true
+		case r.Method == "GET" && r.URL.Path == variablesPath+"/{variable}" && stored.UUID != "":
+		case r.Method == "DELETE" && r.URL.Path == variablesPath+"/{variable}" && stored.UUID != "":
+			stored = RepositoryVariable{}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// The value of a secured variable is never sent back
+		response := stored
+		if response.Secured {
+			response.Value = ""
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer closeServer()
+
+	raw := map[string]interface{}{
+		"owner":      "test-owner",
+		"repository": "test-repo",
+		"deployment": "{environment}",
+		"key":        "TOKEN",
+		"value":      "secret",
+	}
+
+	r := resourceDeploymentVariable()
+	diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !stored.Secured || stored.Value != "secret" {
+		t.Fatalf("expected a secured variable to be created, got %#v", stored)
+	}
+	if state.ID != "{environment}/{variable}" || state.Attributes["value"] != "secret" {
+		t.Fatalf("unexpected state %#v", state)
+	}
+
+	state, err = r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected no diff, got %#v", diff.Attributes)
+	}
+
+	raw["value"] = "rotated"
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state, err = r.Apply(state, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !updated || stored.Value != "rotated" {
+		t.Fatalf("expected the value to be updated in place, got %#v", stored)
+	}
+
+	// Removed outside of terraform, together with its environment
+	stored = RepositoryVariable{}
+	state, err = r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if state != nil {
+		t.Fatalf("expected the variable to be removed from state, got %#v", state)
+	}
+}
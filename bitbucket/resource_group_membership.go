@@ -0,0 +1,141 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// GroupMember is a user as the 1.0 groups api lists them
+type GroupMember struct {
+	Username    string `json:"username,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	UUID        string `json:"uuid,omitempty"`
+}
+
+func resourceGroupMembership() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceGroupMembershipCreate,
+		Read:   resourceGroupMembershipRead,
+		Delete: resourceGroupMembershipDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"workspace": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"group_slug": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"user": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"uuid": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"display_name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func groupMemberURL(workspace, groupSlug, user string) string {
+	return fmt.Sprintf("1.0/groups/%s/%s/members/%s",
+		workspace,
+		groupSlug,
+		url.PathEscape(user),
+	)
+}
+
+func resourceGroupMembershipCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	// The 1.0 api wants an empty object rather than no body at all
+	_, err := client.Put(groupMemberURL(
+		d.Get("workspace").(string),
+		d.Get("group_slug").(string),
+		d.Get("user").(string),
+	), bytes.NewBufferString("{}"))
+
+	if err != nil {
+		return fmt.Errorf("Failed to add %s to group %s: %s", d.Get("user").(string), d.Get("group_slug").(string), err)
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s/%s",
+		d.Get("workspace").(string),
+		d.Get("group_slug").(string),
+		d.Get("user").(string),
+	))
+
+	return resourceGroupMembershipRead(d, m)
+}
+
+func resourceGroupMembershipRead(d *schema.ResourceData, m interface{}) error {
+	idparts := strings.SplitN(d.Id(), "/", 3)
+	if len(idparts) != 3 {
+		return fmt.Errorf("Incorrect ID format, should match `workspace/group_slug/user`")
+	}
+
+	d.Set("workspace", idparts[0])
+	d.Set("group_slug", idparts[1])
+	d.Set("user", idparts[2])
+
+	client := m.(*Client)
+	membersReq, err := client.Get(fmt.Sprintf("1.0/groups/%s/%s/members", idparts[0], idparts[1]))
+
+	// The whole group is gone, so is the membership
+	if membersReq != nil && membersReq.StatusCode == 404 {
+		log.Printf("[WARN] Group %s/%s not found, removing membership %s from state", idparts[0], idparts[1], d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var members []GroupMember
+
+	decodeerr := json.NewDecoder(membersReq.Body).Decode(&members)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	for _, member := range members {
+		if member.Username == idparts[2] || member.UUID == idparts[2] {
+			d.Set("uuid", member.UUID)
+			d.Set("display_name", member.DisplayName)
+			return nil
+		}
+	}
+
+	// The user was removed outside of terraform, so it is added again
+	log.Printf("[WARN] Group membership %s not found, removing from state", d.Id())
+	d.SetId("")
+	return nil
+}
+
+func resourceGroupMembershipDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+	return client.DeleteIgnoringNotFound(groupMemberURL(
+		d.Get("workspace").(string),
+		d.Get("group_slug").(string),
+		d.Get("user").(string),
+	))
+}
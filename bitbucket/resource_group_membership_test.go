@@ -0,0 +1,91 @@
+package bitbucket
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+const testGroupMembers = `[{"username": "gob", "display_name": "Gob Bluth", "uuid": "{gob}"}]`
+
+func TestGroupMembership_create(t *testing.T) {
+	var put, body string
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			put = r.URL.Path
+			sent, _ := ioutil.ReadAll(r.Body)
+			body = string(sent)
+		}
+		w.Write([]byte(testGroupMembers))
+	}))
+	defer closeServer()
+
+	r := resourceGroupMembership()
+	diff, err := r.Diff(nil, testResourceConfig(t, map[string]interface{}{
+		"workspace":  "test-owner",
+		"group_slug": "developers",
+		"user":       "{gob}",
+	}), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if put != "/1.0/groups/test-owner/developers/members/{gob}" || body != "{}" {
+		t.Fatalf("expected the member to be PUT with an empty object, got %q %q", put, body)
+	}
+	if state.ID != "test-owner/developers/{gob}" || state.Attributes["display_name"] != "Gob Bluth" {
+		t.Fatalf("unexpected state %#v", state)
+	}
+}
+
+func TestGroupMembership_import(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(map[string]string{
+		"/1.0/groups/test-owner/developers/members": testGroupMembers,
+	}))
+	defer closeServer()
+
+	d := resourceGroupMembership().Data(&terraform.InstanceState{ID: "test-owner/developers/gob"})
+	if err := resourceGroupMembershipRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for key, expected := range map[string]string{
+		"workspace":    "test-owner",
+		"group_slug":   "developers",
+		"user":         "gob",
+		"uuid":         "{gob}",
+		"display_name": "Gob Bluth",
+	} {
+		if v := d.Get(key).(string); v != expected {
+			t.Fatalf("expected %s to be %q, got %q", key, expected, v)
+		}
+	}
+}
+
+func TestGroupMembership_removedOutsideOfTerraform(t *testing.T) {
+	for name, responses := range map[string]map[string]string{
+		"user left the group": {"/1.0/groups/test-owner/developers/members": `[]`},
+		"group deleted":       nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, closeServer := testClient(t, testResponses(responses))
+			defer closeServer()
+
+			d := resourceGroupMembership().Data(&terraform.InstanceState{ID: "test-owner/developers/gob"})
+			if err := resourceGroupMembershipRead(d, client); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if d.Id() != "" {
+				t.Fatalf("expected the membership to be removed from state, got %s", d.Id())
+			}
+		})
+	}
+}
@@ -16,8 +16,8 @@ type Hook struct {
 	UUID                 string   `json:"uuid,omitempty"`
 	URL                  string   `json:"url,omitempty"`
 	Description          string   `json:"description,omitempty"`
-	Active               bool     `json:"active,omitempty"`
-	SkipCertVerification bool     `json:"skip_cert_verification,omitempty"`
+	Active               bool     `json:"active"`
+	SkipCertVerification bool     `json:"skip_cert_verification"`
 	Events               []string `json:"events,omitempty"`
 }
 
@@ -29,6 +29,8 @@ func resourceHook() *schema.Resource {
 		Delete: resourceHookDelete,
 		Exists: resourceHookExists,
 
+		CustomizeDiff: resourceHookCustomizeDiff,
+
 		Schema: map[string]*schema.Schema{
 			"owner": {
 				Type:     schema.TypeString,
@@ -72,6 +74,16 @@ func resourceHook() *schema.Resource {
 	}
 }
 
+// resourceHookCustomizeDiff only checks events that changed, bitbucket keeps hooks on events it
+// no longer offers working and those shouldn't start failing the plan
+func resourceHookCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	if !d.HasChange("events") || !d.NewValueKnown("events") {
+		return nil
+	}
+
+	return validateWebhookEvents(m, "repository", stringSetList(stringSet(d.Get("events"))))
+}
+
 func createHook(d *schema.ResourceData) *Hook {
 
 	events := make([]string, 0, len(d.Get("events").(*schema.Set).List()))
@@ -98,7 +110,7 @@ func resourceHookCreate(d *schema.ResourceData, m interface{}) error {
 		return err
 	}
 
-	hookReq, err := client.Post(fmt.Sprintf("2.0/repositories/%s/%s/hooks",
+	hookReq, err := client.Post(fmt.Sprintf("repositories/%s/%s/hooks",
 		d.Get("owner").(string),
 		d.Get("repository").(string),
 	), bytes.NewBuffer(payload))
@@ -124,18 +136,23 @@ func resourceHookCreate(d *schema.ResourceData, m interface{}) error {
 func resourceHookRead(d *schema.ResourceData, m interface{}) error {
 	client := m.(*Client)
 
-	hookReq, err := client.Get(fmt.Sprintf("2.0/repositories/%s/%s/hooks/%s",
+	hookReq, err := client.Get(fmt.Sprintf("repositories/%s/%s/hooks/%s",
 		d.Get("owner").(string),
 		d.Get("repository").(string),
 		url.PathEscape(d.Id()),
 	))
 
+	// The hook was removed outside of terraform
+	if hookReq != nil && hookReq.StatusCode == 404 {
+		log.Printf("[WARN] Hook %s not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
 	if err != nil {
 		return err
 	}
 
-	log.Printf("ID: %s", url.PathEscape(d.Id()))
-
 	if hookReq.StatusCode == 200 {
 		var hook Hook
 
@@ -175,7 +192,7 @@ func resourceHookUpdate(d *schema.ResourceData, m interface{}) error {
 		return err
 	}
 
-	_, err = client.Put(fmt.Sprintf("2.0/repositories/%s/%s/hooks/%s",
+	_, err = client.Put(fmt.Sprintf("repositories/%s/%s/hooks/%s",
 		d.Get("owner").(string),
 		d.Get("repository").(string),
 		url.PathEscape(d.Id()),
@@ -191,7 +208,7 @@ func resourceHookUpdate(d *schema.ResourceData, m interface{}) error {
 func resourceHookExists(d *schema.ResourceData, m interface{}) (bool, error) {
 	client := m.(*Client)
 	if _, okay := d.GetOk("uuid"); okay {
-		hookReq, err := client.Get(fmt.Sprintf("2.0/repositories/%s/%s/hooks/%s",
+		hookReq, err := client.Get(fmt.Sprintf("repositories/%s/%s/hooks/%s",
 			d.Get("owner").(string),
 			d.Get("repository").(string),
 			url.PathEscape(d.Id()),
@@ -201,11 +218,11 @@ func resourceHookExists(d *schema.ResourceData, m interface{}) (bool, error) {
 			log.Printf("[DEBUG] Req: %+v, Err: %+v", hookReq, err)
 			// If the hook was not found, we get the message "is not a valid hook".
 			// Return nil so we can show that the hook is gone.
-			if hookReq.StatusCode == 404 {
+			if hookReq != nil && hookReq.StatusCode == 404 {
 				return false, nil
 			}
 
-			panic(err)
+			return false, err
 		}
 
 		if hookReq.StatusCode != 200 {
@@ -221,12 +238,10 @@ func resourceHookExists(d *schema.ResourceData, m interface{}) (bool, error) {
 
 func resourceHookDelete(d *schema.ResourceData, m interface{}) error {
 	client := m.(*Client)
-	_, err := client.Delete(fmt.Sprintf("2.0/repositories/%s/%s/hooks/%s",
+	return client.DeleteIgnoringNotFound(fmt.Sprintf("repositories/%s/%s/hooks/%s",
 		d.Get("owner").(string),
 		d.Get("repository").(string),
 		url.PathEscape(d.Id()),
 	))
 
-	return err
-
 }
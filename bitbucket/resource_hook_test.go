@@ -2,6 +2,7 @@ package bitbucket
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
 	"testing"
@@ -78,3 +79,53 @@ func testAccCheckBitbucketHookExists(n string, hook *Hook) resource.TestCheckFun
 		return nil
 	}
 }
+
+func TestWebhook_eventsOrderIsNotDrift(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo/hooks/{hook}": `{"uuid": "{hook}", "url": "https://example.com", "description": "Deploy",
+			"active": true, "skip_cert_verification": true, "events": ["pullrequest:created", "repo:push"]}`,
+	}))
+	defer closeServer()
+
+	raw := map[string]interface{}{
+		"owner":       "test-owner",
+		"repository":  "test-repo",
+		"url":         "https://example.com",
+		"description": "Deploy",
+		"events":      []interface{}{"repo:push", "pullrequest:created"},
+	}
+
+	r := resourceHook()
+	state, err := r.Refresh(&terraform.InstanceState{
+		ID:         "{hook}",
+		Attributes: map[string]string{"owner": "test-owner", "repository": "test-repo", "uuid": "{hook}"},
+	}, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err := r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected no diff, got %#v", diff.Attributes)
+	}
+}
+
+func TestWebhook_deletedOutsideOfTerraform(t *testing.T) {
+	client, closeServer := testClient(t, http.NotFoundHandler())
+	defer closeServer()
+
+	d := resourceHook().Data(&terraform.InstanceState{
+		ID:         "{hook}",
+		Attributes: map[string]string{"owner": "test-owner", "repository": "test-repo", "uuid": "{hook}"},
+	})
+
+	if err := resourceHookRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.Id() != "" {
+		t.Fatalf("expected the hook to be removed from state, got %s", d.Id())
+	}
+}
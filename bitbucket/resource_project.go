@@ -5,17 +5,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
+	"strings"
 
 	"github.com/hashicorp/terraform/helper/schema"
-	"strings"
 )
 
 // Project is the project data we need to send to create a project on the bitbucket api
 type Project struct {
 	Key         string `json:"key,omitempty"`
-	IsPrivate   bool   `json:"is_private,omitempty"`
+	IsPrivate   bool   `json:"is_private"`
 	Owner       string `json:"owner.username,omitempty"`
-	Description string `json:"description,omitempty"`
+	Description string `json:"description"`
 	Name        string `json:"name,omitempty"`
 	UUID        string `json:"uuid,omitempty"`
 }
@@ -26,11 +27,16 @@ func resourceProject() *schema.Resource {
 		Update: resourceProjectUpdate,
 		Read:   resourceProjectRead,
 		Delete: resourceProjectDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
 
 		Schema: map[string]*schema.Schema{
 			"key": {
 				Type:     schema.TypeString,
 				Required: true,
+				// Bitbucket treats the key as the identity of the project
+				ForceNew: true,
 			},
 			"is_private": {
 				Type:     schema.TypeBool,
@@ -41,14 +47,29 @@ func resourceProject() *schema.Resource {
 				Type:     schema.TypeString,
 				Optional: true,
 			},
+			"workspace": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"owner"},
+			},
 			"owner": {
-				Type:     schema.TypeString,
-				Required: true,
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ForceNew:      true,
+				Deprecated:    "use workspace instead",
+				ConflictsWith: []string{"workspace"},
 			},
 			"name": {
 				Type:     schema.TypeString,
 				Required: true,
 			},
+			"uuid": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
 		},
 	}
 }
@@ -64,26 +85,37 @@ func newProjectFromResource(d *schema.ResourceData) *Project {
 	return project
 }
 
+// projectWorkspace returns the workspace the project is in, owner is what it used to be called
+func projectWorkspace(d *schema.ResourceData) (string, error) {
+	if workspace := d.Get("workspace").(string); workspace != "" {
+		return workspace, nil
+	}
+
+	if owner := d.Get("owner").(string); owner != "" {
+		return owner, nil
+	}
+
+	return "", fmt.Errorf("workspace must not be a empty string")
+}
+
 func resourceProjectUpdate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*Client)
 	project := newProjectFromResource(d)
 
-	var jsonbuffer []byte
-
-	jsonpayload := bytes.NewBuffer(jsonbuffer)
-	enc := json.NewEncoder(jsonpayload)
-	enc.Encode(project)
+	workspace, err := projectWorkspace(d)
+	if err != nil {
+		return err
+	}
 
-	var projectKey string
-	projectKey = d.Get("key").(string)
-	if projectKey == "" {
-		projectKey = d.Get("key").(string)
+	bytedata, err := json.Marshal(project)
+	if err != nil {
+		return err
 	}
 
-	_, err := client.Put(fmt.Sprintf("2.0/teams/%s/projects/%s",
-		d.Get("owner").(string),
-		projectKey,
-	), jsonpayload)
+	_, err = client.Put(fmt.Sprintf("workspaces/%s/projects/%s",
+		workspace,
+		d.Get("key").(string),
+	), bytes.NewBuffer(bytedata))
 
 	if err != nil {
 		return err
@@ -96,96 +128,92 @@ func resourceProjectCreate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*Client)
 	project := newProjectFromResource(d)
 
-	bytedata, err := json.Marshal(project)
-
+	workspace, err := projectWorkspace(d)
 	if err != nil {
 		return err
 	}
 
-	var projectKey string
-	projectKey = d.Get("key").(string)
-	if projectKey == "" {
-		projectKey = d.Get("key").(string)
-	}
-
-	owner := d.Get("owner").(string)
-	if owner == "" {
-		return fmt.Errorf("owner must not be a empty string")
+	bytedata, err := json.Marshal(project)
+	if err != nil {
+		return err
 	}
 
-	_, err = client.Post(fmt.Sprintf("2.0/teams/%s/projects/",
-		d.Get("owner").(string),
+	_, err = client.Post(fmt.Sprintf("workspaces/%s/projects",
+		workspace,
 	), bytes.NewBuffer(bytedata))
 
 	if err != nil {
 		return err
 	}
 
-	d.SetId(string(fmt.Sprintf("%s/%s", d.Get("owner").(string), projectKey)))
+	d.SetId(string(fmt.Sprintf("%s/%s", workspace, d.Get("key").(string))))
 
 	return resourceProjectRead(d, m)
 }
 
 func resourceProjectRead(d *schema.ResourceData, m interface{}) error {
-	id := d.Id()
-	if id != "" {
-		idparts := strings.Split(id, "/")
-		if len(idparts) == 2 {
-			d.Set("owner", idparts[0])
-			d.Set("key", idparts[1])
-		} else {
-			return fmt.Errorf("Incorrect ID format, should match `owner/key`")
-		}
+	idparts := strings.Split(d.Id(), "/")
+	if len(idparts) != 2 {
+		return fmt.Errorf("Incorrect ID format, should match `workspace/key`")
 	}
 
-	var projectKey string
-	projectKey = d.Get("key").(string)
-	if projectKey == "" {
-		projectKey = d.Get("key").(string)
+	workspace := idparts[0]
+	d.Set("key", idparts[1])
+
+	// Only track the argument the project was configured with, so using either isn't a diff
+	if d.Get("owner").(string) != "" && d.Get("workspace").(string) == "" {
+		d.Set("owner", workspace)
+	} else {
+		d.Set("workspace", workspace)
 	}
 
 	client := m.(*Client)
-	projectReq, _ := client.Get(fmt.Sprintf("2.0/teams/%s/projects/%s",
-		d.Get("owner").(string),
-		projectKey,
+	projectReq, err := client.Get(fmt.Sprintf("workspaces/%s/projects/%s",
+		workspace,
+		idparts[1],
 	))
 
-	if projectReq.StatusCode == 200 {
+	if projectReq != nil && projectReq.StatusCode == 404 {
+		log.Printf("[WARN] Project %s not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
 
-		var project Project
+	if err != nil {
+		return err
+	}
 
-		body, readerr := ioutil.ReadAll(projectReq.Body)
-		if readerr != nil {
-			return readerr
-		}
+	var project Project
 
-		decodeerr := json.Unmarshal(body, &project)
-		if decodeerr != nil {
-			return decodeerr
-		}
+	body, readerr := ioutil.ReadAll(projectReq.Body)
+	if readerr != nil {
+		return readerr
+	}
 
-		d.Set("key", project.Key)
-		d.Set("is_private", project.IsPrivate)
-		d.Set("name", project.Name)
-		d.Set("description", project.Description)
+	decodeerr := json.Unmarshal(body, &project)
+	if decodeerr != nil {
+		return decodeerr
 	}
 
+	d.Set("key", project.Key)
+	d.Set("is_private", project.IsPrivate)
+	d.Set("name", project.Name)
+	d.Set("description", project.Description)
+	d.Set("uuid", project.UUID)
+
 	return nil
 }
 
 func resourceProjectDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
 
-	var projectKey string
-	projectKey = d.Get("key").(string)
-	if projectKey == "" {
-		projectKey = d.Get("key").(string)
+	workspace, err := projectWorkspace(d)
+	if err != nil {
+		return err
 	}
 
-	client := m.(*Client)
-	_, err := client.Delete(fmt.Sprintf("2.0/teams/%s/projects/%s",
-		d.Get("owner").(string),
-		projectKey,
+	return client.DeleteIgnoringNotFound(fmt.Sprintf("workspaces/%s/projects/%s",
+		workspace,
+		d.Get("key").(string),
 	))
-
-	return err
 }
@@ -0,0 +1,178 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+func resourceProjectBranchRestriction() *schema.Resource {
+	return &schema.Resource{
+		Create:        resourceProjectBranchRestrictionCreate,
+		Read:          resourceProjectBranchRestrictionRead,
+		Update:        resourceProjectBranchRestrictionUpdate,
+		Delete:        resourceProjectBranchRestrictionDelete,
+		CustomizeDiff: resourceBranchRestrictionsCustomizeDiff,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"workspace": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"project_key": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"kind": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice(branchRestrictionKinds, false),
+			},
+			// A project has no main branch to default to
+			"pattern": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"users": {
+				Type:     schema.TypeSet,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Optional: true,
+				Set:      schema.HashString,
+			},
+			"groups": {
+				Type:     schema.TypeSet,
+				Elem:     branchRestrictionGroupResource(),
+				Optional: true,
+			},
+			"value": {
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func projectBranchRestrictionsURL(workspace, projectKey string) string {
+	return fmt.Sprintf("workspaces/%s/projects/%s/branch-restrictions", workspace, projectKey)
+}
+
+// projectBranchRestrictionURL splits the `workspace/project_key/id` ID into the url of the restriction
+func projectBranchRestrictionURL(d *schema.ResourceData) (string, error) {
+	idparts := strings.SplitN(d.Id(), "/", 3)
+	if len(idparts) != 3 {
+		return "", fmt.Errorf("Incorrect ID format, should match `workspace/project_key/id`")
+	}
+
+	d.Set("workspace", idparts[0])
+	d.Set("project_key", idparts[1])
+
+	return fmt.Sprintf("%s/%s", projectBranchRestrictionsURL(idparts[0], idparts[1]), idparts[2]), nil
+}
+
+func resourceProjectBranchRestrictionCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	bytedata, err := json.Marshal(createBranchRestriction(d))
+	if err != nil {
+		return err
+	}
+
+	branchRestrictionReq, err := client.Post(projectBranchRestrictionsURL(
+		d.Get("workspace").(string),
+		d.Get("project_key").(string),
+	), bytes.NewBuffer(bytedata))
+
+	if err != nil {
+		return err
+	}
+
+	var created BranchRestriction
+
+	decodeerr := json.NewDecoder(branchRestrictionReq.Body).Decode(&created)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s/%d", d.Get("workspace").(string), d.Get("project_key").(string), created.ID))
+
+	return resourceProjectBranchRestrictionRead(d, m)
+}
+
+func resourceProjectBranchRestrictionRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	restrictionURL, err := projectBranchRestrictionURL(d)
+	if err != nil {
+		return err
+	}
+
+	branchRestrictionReq, err := client.Get(restrictionURL)
+
+	// Removed outside of terraform, clearing the ID makes the plan create it again
+	if branchRestrictionReq != nil && branchRestrictionReq.StatusCode == 404 {
+		log.Printf("[WARN] Project branch restriction %s not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var branchRestriction BranchRestriction
+
+	decodeerr := json.NewDecoder(branchRestrictionReq.Body).Decode(&branchRestriction)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	d.Set("kind", branchRestriction.Kind)
+	d.Set("pattern", branchRestriction.Pattern)
+	d.Set("value", branchRestriction.Value)
+	d.Set("users", flattenBranchRestrictionUsers(branchRestriction.Users))
+	d.Set("groups", flattenBranchRestrictionGroups(branchRestriction.Groups))
+
+	return nil
+}
+
+func resourceProjectBranchRestrictionUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	restrictionURL, err := projectBranchRestrictionURL(d)
+	if err != nil {
+		return err
+	}
+
+	bytedata, err := json.Marshal(createBranchRestriction(d))
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Put(restrictionURL, bytes.NewBuffer(bytedata))
+	if err != nil {
+		return err
+	}
+
+	return resourceProjectBranchRestrictionRead(d, m)
+}
+
+func resourceProjectBranchRestrictionDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	restrictionURL, err := projectBranchRestrictionURL(d)
+	if err != nil {
+		return err
+	}
+
+	return client.DeleteIgnoringNotFound(restrictionURL)
+}
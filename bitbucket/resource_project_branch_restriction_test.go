@@ -0,0 +1,96 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestProjectBranchRestriction_lifecycle(t *testing.T) {
+	var restriction *BranchRestriction
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/2.0/workspaces/test-owner/projects/PROJ/branch-restrictions":
+			restriction = &BranchRestriction{}
+			json.NewDecoder(r.Body).Decode(restriction)
+			restriction.ID = 7
+		case r.URL.Path != "/2.0/workspaces/test-owner/projects/PROJ/branch-restrictions/7":
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case r.Method == "PUT":
+			json.NewDecoder(r.Body).Decode(restriction)
+			restriction.ID = 7
+		case r.Method == "DELETE":
+			restriction = nil
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if restriction == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(restriction)
+	}))
+	defer closeServer()
+
+	raw := func(value int) map[string]interface{} {
+		return map[string]interface{}{
+			"workspace":   "test-owner",
+			"project_key": "PROJ",
+			"kind":        "require_approvals_to_merge",
+			"pattern":     "release/*",
+			"value":       value,
+		}
+	}
+
+	r := resourceProjectBranchRestriction()
+	var state *terraform.InstanceState
+
+	for _, value := range []int{1, 2} {
+		diff, err := r.Diff(state, testResourceConfig(t, raw(value)), client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if state, err = r.Apply(state, diff, client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if restriction.Value != value || restriction.Pattern != "release/*" || state.ID != "test-owner/PROJ/7" {
+			t.Fatalf("expected %d approvals, got %#v and state %#v", value, restriction, state)
+		}
+	}
+
+	// Removed outside of terraform
+	restriction = nil
+
+	state, err := r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if state != nil {
+		t.Fatalf("expected the restriction to be removed from state, got %#v", state)
+	}
+}
+
+func TestProjectBranchRestriction_import(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(map[string]string{
+		"/2.0/workspaces/test-owner/projects/PROJ/branch-restrictions/7": `{"id": 7, "kind": "push", "pattern": "main", "users": [{"username": "gob"}]}`,
+	}))
+	defer closeServer()
+
+	state, err := resourceProjectBranchRestriction().Refresh(&terraform.InstanceState{ID: "test-owner/PROJ/7"}, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if state.Attributes["workspace"] != "test-owner" || state.Attributes["project_key"] != "PROJ" ||
+		state.Attributes["kind"] != "push" || state.Attributes["users.#"] != "1" {
+		t.Fatalf("unexpected state %#v", state.Attributes)
+	}
+}
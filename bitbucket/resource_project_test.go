@@ -1,7 +1,9 @@
 package bitbucket
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"testing"
 
@@ -15,7 +17,7 @@ func TestAccBitbucketProject_basic(t *testing.T) {
 	testTeam := os.Getenv("BITBUCKET_TEAM")
 	testAccBitbucketProjectConfig := fmt.Sprintf(`
 		resource "bitbucket_project" "test_project" {
-			owner = "%s"
+			workspace = "%s"
 			name = "test-project-for-project-test"
 			key = "TESTPROJ" 
 		}
@@ -43,7 +45,7 @@ func testAccCheckBitbucketProjectDestroy(s *terraform.State) error {
 		return fmt.Errorf("Not found %s", "bitbucket_project.test_project")
 	}
 
-	response, _ := client.Get(fmt.Sprintf("2.0/teams/%s/projects/%s", rs.Primary.Attributes["owner"], rs.Primary.Attributes["name"]))
+	response, _ := client.Get(fmt.Sprintf("2.0/workspaces/%s/projects/%s", rs.Primary.Attributes["workspace"], rs.Primary.Attributes["key"]))
 
 	if response.StatusCode != 404 {
 		return fmt.Errorf("Project still exists")
@@ -64,3 +66,105 @@ func testAccCheckBitbucketProjectExists(n string, project *Project) resource.Tes
 		return nil
 	}
 }
+
+func TestProject_createAndDrift(t *testing.T) {
+	var created Project
+	stored := `{"key": "PROJ", "name": "Project", "description": "", "is_private": true, "uuid": "{project}"}`
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/2.0/workspaces/test-workspace/projects":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(stored))
+		case r.Method == "GET" && r.URL.Path == "/2.0/workspaces/test-workspace/projects/PROJ":
+			w.Write([]byte(stored))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer closeServer()
+
+	raw := map[string]interface{}{
+		"workspace": "test-workspace",
+		"key":       "PROJ",
+		"name":      "Project",
+	}
+
+	r := resourceProject()
+	diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if created.Key != "PROJ" || !created.IsPrivate {
+		t.Fatalf("unexpected project created %#v", created)
+	}
+	if state.ID != "test-workspace/PROJ" || state.Attributes["uuid"] != "{project}" {
+		t.Fatalf("unexpected state %#v", state)
+	}
+
+	// Renamed and made public outside of terraform
+	stored = `{"key": "PROJ", "name": "Renamed", "description": "changed", "is_private": false, "uuid": "{project}"}`
+
+	state, err = r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, key := range []string{"name", "description", "is_private"} {
+		if _, ok := diff.Attributes[key]; !ok {
+			t.Fatalf("expected drift of %s to show, got %#v", key, diff.Attributes)
+		}
+	}
+	if diff.RequiresNew() {
+		t.Fatal("expected the drift to be fixed in place")
+	}
+
+	raw["key"] = "OTHER"
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.RequiresNew() {
+		t.Fatal("expected changing the key to replace the project")
+	}
+}
+
+func TestProject_ownerIsStillSupported(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(map[string]string{
+		"/2.0/workspaces/test-workspace/projects/PROJ": `{"key": "PROJ", "name": "Project", "is_private": true}`,
+	}))
+	defer closeServer()
+
+	raw := map[string]interface{}{
+		"owner": "test-workspace",
+		"key":   "PROJ",
+		"name":  "Project",
+	}
+
+	r := resourceProject()
+	state, err := r.Refresh(&terraform.InstanceState{
+		ID:         "test-workspace/PROJ",
+		Attributes: map[string]string{"owner": "test-workspace"},
+	}, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err := r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected no diff, got %#v", diff.Attributes)
+	}
+}
@@ -5,10 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
+	"net/url"
+	"time"
 
 	"strings"
+	"unicode/utf8"
 
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
 )
 
 // CloneURL is the internal struct we use to represent urls
@@ -22,25 +27,52 @@ type PipelinesEnabled struct {
 	Enabled bool `json:"enabled"`
 }
 
-// Repository is the struct we need to send off to the Bitbucket API to create a repository
+// MainBranch is the branch bitbucket treats as the default for a repository
+type MainBranch struct {
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
+}
+
+// Repository is the struct we need to send off to the Bitbucket API to create a repository.
+// It only models some of the fields of a repository, so updates send repositoryUpdatePayload
+// instead to leave the fields it doesn't know about alone.
 type Repository struct {
 	SCM         string `json:"scm,omitempty"`
 	HasWiki     bool   `json:"has_wiki,omitempty"`
 	HasIssues   bool   `json:"has_issues,omitempty"`
 	Website     string `json:"website,omitempty"`
-	IsPrivate   bool   `json:"is_private,omitempty"`
+	IsPrivate   bool   `json:"is_private"`
 	ForkPolicy  string `json:"fork_policy,omitempty"`
 	Language    string `json:"language,omitempty"`
 	Description string `json:"description,omitempty"`
 	Name        string `json:"name,omitempty"`
 	Slug        string `json:"slug,omitempty"`
 	UUID        string `json:"uuid,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	CreatedOn   string `json:"created_on,omitempty"`
+	UpdatedOn   string `json:"updated_on,omitempty"`
 	Project     struct {
-		Key string `json:"key,omitempty"`
+		Key  string `json:"key,omitempty"`
+		Name string `json:"name,omitempty"`
 	} `json:"project,omitempty"`
 	Links struct {
-		Clone []CloneURL `json:"clone,omitempty"`
+		Clone  []CloneURL `json:"clone,omitempty"`
+		Avatar struct {
+			Href string `json:"href,omitempty"`
+		} `json:"avatar,omitempty"`
 	} `json:"links,omitempty"`
+	Mainbranch *MainBranch `json:"mainbranch,omitempty"`
+	Parent     *struct {
+		FullName string `json:"full_name,omitempty"`
+	} `json:"parent,omitempty"`
+}
+
+// RepositoryFork is what the forks endpoint takes, the repository to create in the workspace
+type RepositoryFork struct {
+	*Repository
+	Workspace struct {
+		Slug string `json:"slug"`
+	} `json:"workspace"`
 }
 
 func resourceRepository() *schema.Resource {
@@ -52,26 +84,40 @@ func resourceRepository() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		CustomizeDiff: resourceRepositoryCustomizeDiff,
 
 		Schema: map[string]*schema.Schema{
 			"scm": {
-				Type:     schema.TypeString,
-				Optional: true,
-				Default:  "git",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"git", "hg"}, false),
 			},
 			"has_wiki": {
-				Type:     schema.TypeBool,
-				Optional: true,
-				Default:  false,
+				Type:             schema.TypeBool,
+				Optional:         true,
+				Default:          false,
+				DiffSuppressFunc: suppressFeatureDisabledDiff("wiki_disabled_by_workspace"),
 			},
 			"has_issues": {
+				Type:             schema.TypeBool,
+				Optional:         true,
+				Default:          false,
+				DiffSuppressFunc: suppressFeatureDisabledDiff("issues_disabled_by_workspace"),
+			},
+			"wiki_disabled_by_workspace": {
 				Type:     schema.TypeBool,
-				Optional: true,
-				Default:  false,
+				Computed: true,
+			},
+			"issues_disabled_by_workspace": {
+				Type:     schema.TypeBool,
+				Computed: true,
 			},
 			"website": {
-				Type:     schema.TypeString,
-				Optional: true,
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateRepositoryWebsite,
 			},
 			"clone_ssh": {
 				Type:     schema.TypeString,
@@ -81,9 +127,149 @@ func resourceRepository() *schema.Resource {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
+			"wiki_clone_ssh": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"wiki_clone_https": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
 			"project_key": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				DiffSuppressFunc: suppressDefaultProjectDiff,
+			},
+			"uuid": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"project_name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"fork_of": {
+				Type:     schema.TypeList,
+				Optional: true,
+				ForceNew: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"owner": {
+							Type:     schema.TypeString,
+							Required: true,
+							ForceNew: true,
+						},
+						"slug": {
+							Type:     schema.TypeString,
+							Required: true,
+							ForceNew: true,
+						},
+					},
+				},
+			},
+			"parent_full_name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"avatar_url": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"main_branch": {
 				Type:     schema.TypeString,
 				Optional: true,
+				Computed: true,
+			},
+			"archived": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"branching_model_settings": branchingModelSettingsSchema(),
+			"webhook":                  repositoryWebhookSchema(),
+			"branching_model_matches_project": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"branch_restriction": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"kind": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.StringInSlice(branchRestrictionKinds, false),
+						},
+						"pattern": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"users": {
+							Type:     schema.TypeSet,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+							Optional: true,
+							Set:      schema.HashString,
+						},
+						"groups": {
+							Type:     schema.TypeSet,
+							Elem:     branchRestrictionGroupResource(),
+							Optional: true,
+						},
+						"value": {
+							Type:     schema.TypeInt,
+							Optional: true,
+						},
+					},
+				},
+			},
+			"environment": repositoryEnvironmentSchema(),
+			"warn_on_destroy": {
+				Type:     schema.TypeBool,
+				Optional: true,
+			},
+			"open_pull_requests": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"deploy_key_count": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"webhook_count": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"read_issue_tracker": {
+				Type:     schema.TypeBool,
+				Optional: true,
+			},
+			"issue_tracker": repositoryIssueTrackerSchema(),
+			"size": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"size_warn_threshold": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"size_over_threshold": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"created_on": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"updated_on": {
+				Type:     schema.TypeString,
+				Computed: true,
 			},
 			"is_private": {
 				Type:     schema.TypeBool,
@@ -93,20 +279,27 @@ func resourceRepository() *schema.Resource {
 			"pipelines_enabled": {
 				Type:     schema.TypeBool,
 				Optional: true,
-				Default:  false,
+				Computed: true,
 			},
 			"fork_policy": {
 				Type:     schema.TypeString,
 				Optional: true,
 				Default:  "allow_forks",
+				ValidateFunc: validation.StringInSlice([]string{
+					"allow_forks",
+					"no_public_forks",
+					"no_forks",
+				}, false),
 			},
 			"language": {
-				Type:     schema.TypeString,
-				Optional: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				DiffSuppressFunc: suppressLanguageDiff,
 			},
 			"description": {
-				Type:     schema.TypeString,
-				Optional: true,
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateMaxLength(maxRepositoryDescriptionLength),
 			},
 			"owner": {
 				Type:     schema.TypeString,
@@ -125,6 +318,118 @@ func resourceRepository() *schema.Resource {
 	}
 }
 
+func resourceRepositoryCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	sizeWarning := repositorySizeWarning(d.Id(), d.Get("size").(int), d.Get("size_warn_threshold").(int))
+	if sizeWarning != "" {
+		log.Printf("[WARN] %s", sizeWarning)
+	}
+
+	// A new threshold is compared with the size from the last refresh so the plan shows the outcome
+	if d.Id() != "" && d.HasChange("size_warn_threshold") {
+		if err := d.SetNew("size_over_threshold", sizeWarning != ""); err != nil {
+			return err
+		}
+	}
+
+	// Unlike a destroy, replacing the repository is planned by the provider so it can be warned about here
+	if client, ok := m.(*Client); ok && d.Id() != "" && d.Get("warn_on_destroy").(bool) && (d.HasChange("scm") || d.HasChange("fork_of")) {
+		idparts := strings.SplitN(d.Id(), "/", 2)
+		if warning := repositoryDependentsWarning(client, idparts[0], idparts[len(idparts)-1]); warning != "" {
+			log.Printf("[WARN] The repository is replaced. %s", warning)
+		}
+	}
+
+	// Only a fork starts out with branches, any other new repository is empty until the first push
+	if d.Id() == "" && d.Get("main_branch").(string) != "" && len(d.Get("fork_of").([]interface{})) == 0 {
+		return fmt.Errorf("A new repository has no branches yet, set main_branch after the first push")
+	}
+
+	if d.NewValueKnown("is_private") && d.NewValueKnown("fork_policy") {
+		if err := validateForkPolicy(d.Get("is_private").(bool), d.Get("fork_policy").(string)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateForkPolicy catches fork policies bitbucket refuses for a repository at plan time, only
+// private repositories can limit forks to private ones
+func validateForkPolicy(isPrivate bool, forkPolicy string) error {
+	if !isPrivate && forkPolicy == "no_public_forks" {
+		return fmt.Errorf("fork_policy no_public_forks is only valid for a private repository, a public " +
+			"repository can use allow_forks or no_forks")
+	}
+	return nil
+}
+
+// repositorySizeWarning describes a repository that grew past size_warn_threshold, it is only ever a
+// warning so plans are never blocked by it
+func repositorySizeWarning(id string, size, threshold int) string {
+	if threshold <= 0 || size <= threshold {
+		return ""
+	}
+	return fmt.Sprintf("Repository %s is %d bytes, which is over the size_warn_threshold of %d bytes", id, size, threshold)
+}
+
+// validateHTTPURL makes sure a non empty value is an absolute http or https URL, bitbucket
+// stores whatever it is given so a missing scheme would otherwise go unnoticed
+func validateHTTPURL(v interface{}, k string) (ws []string, errors []error) {
+	value := v.(string)
+	if value == "" {
+		return
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errors = append(errors, fmt.Errorf("%q must be an absolute http or https URL, got: %s", k, value))
+	}
+
+	return
+}
+
+// The longest description and website bitbucket accepts, anything longer is rejected with a 400
+const (
+	maxRepositoryDescriptionLength = 2048
+	maxRepositoryWebsiteLength     = 255
+)
+
+// validateMaxLength makes sure a value is at most max characters long, an empty value is always valid
+func validateMaxLength(max int) schema.SchemaValidateFunc {
+	return func(v interface{}, k string) (ws []string, errors []error) {
+		if length := utf8.RuneCountInString(v.(string)); length > max {
+			errors = append(errors, fmt.Errorf("%q must be at most %d characters long, got %d", k, max, length))
+		}
+		return
+	}
+}
+
+func validateRepositoryWebsite(v interface{}, k string) (ws []string, errors []error) {
+	ws, errors = validateMaxLength(maxRepositoryWebsiteLength)(v, k)
+	if len(errors) > 0 {
+		return
+	}
+
+	return validateHTTPURL(v, k)
+}
+
+// suppressLanguageDiff ignores casing differences, bitbucket has no way to stop it normalizing
+// the language so "Go" always comes back as "go"
+func suppressLanguageDiff(k, old, new string, d *schema.ResourceData) bool {
+	return strings.EqualFold(old, new)
+}
+
+// suppressDefaultProjectDiff ignores the project bitbucket put the repository in when the config
+// doesn't pick one, workspaces put new repositories in their default project. A project given by
+// name is stored by its key, so the name of the project the repository is in isn't a diff either.
+// Bitbucket uppercases keys, a key written in another casing is the same project.
+func suppressDefaultProjectDiff(k, old, new string, d *schema.ResourceData) bool {
+	if new == "" {
+		return old != ""
+	}
+	return old != "" && (strings.EqualFold(new, old) || strings.EqualFold(new, d.Get("project_name").(string)))
+}
+
 func newRepositoryFromResource(d *schema.ResourceData) *Repository {
 	repo := &Repository{
 		Name:        d.Get("name").(string),
@@ -147,55 +452,253 @@ func resourceRepositoryUpdate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*Client)
 	repository := newRepositoryFromResource(d)
 
-	var jsonbuffer []byte
-
-	jsonpayload := bytes.NewBuffer(jsonbuffer)
-	enc := json.NewEncoder(jsonpayload)
-	enc.Encode(repository)
-
 	var repoSlug string
 	repoSlug = d.Get("slug").(string)
 	if repoSlug == "" {
 		repoSlug = d.Get("name").(string)
 	}
 
-	_, err := client.Put(fmt.Sprintf("2.0/repositories/%s/%s",
-		d.Get("owner").(string),
-		repoSlug,
-	), jsonpayload)
+	if d.HasChange("project_key") && repository.Project.Key != "" {
+		projectKey, err := resolveProjectKey(client, d.Get("owner").(string), repository.Project.Key)
+		if err != nil {
+			return err
+		}
+		d.Set("project_key", projectKey)
+	}
 
-	if err != nil {
-		return err
+	if payload := repositoryUpdatePayload(d); len(payload) > 0 {
+		bytedata, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+
+		_, err = client.Put(fmt.Sprintf("repositories/%s/%s",
+			d.Get("owner").(string),
+			repoSlug,
+		), bytes.NewBuffer(bytedata))
+
+		if err != nil {
+			return err
+		}
 	}
 
-	var pipelinesEnabled bool
-	pipelinesEnabled = d.Get("pipelines_enabled").(bool)
-	pipelinesConfig := &PipelinesEnabled{Enabled: pipelinesEnabled}
+	if err := configureRepository(d, client, repoSlug); err != nil {
+		return err
+	}
 
-	bytedata, err := json.Marshal(pipelinesConfig)
+	if err := resourceRepositoryRead(d, m); err != nil {
+		return err
+	}
 
-	if err != nil {
+	if err := checkPrivacyEnforced(d, repository.IsPrivate, d.Get("is_private").(bool)); err != nil {
 		return err
 	}
 
-	_, err = client.Put(fmt.Sprintf("2.0/repositories/%s/%s/pipelines_config",
-		d.Get("owner").(string),
-		repoSlug), bytes.NewBuffer(bytedata))
+	checkFeaturesEnforced(d, repository)
+	return nil
+}
+
+// repositoryUpdatePayload only holds the fields that changed, bitbucket leaves everything else alone.
+// It is a map rather than a Repository so clearing a field sends an empty value instead of dropping it.
+func repositoryUpdatePayload(d *schema.ResourceData) map[string]interface{} {
+	payload := make(map[string]interface{})
+
+	for _, field := range []string{
+		"name",
+		"slug",
+		"is_private",
+		"website",
+		"language",
+		"has_issues",
+		"has_wiki",
+		"fork_policy",
+		"description",
+	} {
+		if d.HasChange(field) {
+			payload[field] = d.Get(field)
+		}
+	}
+
+	if d.HasChange("project_key") && d.Get("project_key").(string) != "" {
+		payload["project"] = map[string]interface{}{"key": d.Get("project_key").(string)}
+	}
+
+	return payload
+}
+
+// checkFeaturesEnforced catches workspaces that turn wikis or issues off for every repository,
+// bitbucket quietly ignores has_wiki and has_issues there. The state keeps what bitbucket says,
+// and the flags stop that from showing up as a diff on every plan.
+func checkFeaturesEnforced(d *schema.ResourceData, want *Repository) {
+	wikiDisabled := want.HasWiki && !d.Get("has_wiki").(bool)
+	if wikiDisabled {
+		log.Printf("[WARN] Repository %s has no wiki even though has_wiki = true, the workspace %s doesn't "+
+			"allow wikis so set has_wiki = false", d.Id(), d.Get("owner").(string))
+	}
+	d.Set("wiki_disabled_by_workspace", wikiDisabled)
+
+	issuesDisabled := want.HasIssues && !d.Get("has_issues").(bool)
+	if issuesDisabled {
+		log.Printf("[WARN] Repository %s has no issue tracker even though has_issues = true, the workspace %s "+
+			"doesn't allow issue trackers so set has_issues = false", d.Id(), d.Get("owner").(string))
+	}
+	d.Set("issues_disabled_by_workspace", issuesDisabled)
+}
+
+func suppressFeatureDisabledDiff(disabledKey string) schema.SchemaDiffSuppressFunc {
+	return func(k, old, new string, d *schema.ResourceData) bool {
+		return old == "false" && new == "true" && d.Get(disabledKey).(bool)
+	}
+}
+
+// checkPrivacyEnforced catches workspaces that only allow private repositories, bitbucket quietly
+// keeps the repository private so asking for a public one would never stop showing a diff
+func checkPrivacyEnforced(d *schema.ResourceData, wantPrivate, isPrivate bool) error {
+	if isPrivate && !wantPrivate {
+		return fmt.Errorf("Repository %s was kept private by Bitbucket, the workspace %s only allows private "+
+			"repositories so set is_private = true", d.Id(), d.Get("owner").(string))
+	}
+	return nil
+}
+
+// resolveProjectKey makes sure a repository can be put in a project, bitbucket only answers a
+// missing project with a generic bad request. A project_key that isn't the key of a project is
+// looked up by name, some users only know the name shown in the UI. Keys are uppercase in bitbucket,
+// so a key is looked up and returned in uppercase whatever the casing in the config.
+func resolveProjectKey(client *Client, workspace, keyOrName string) (string, error) {
+	projectReq, err := client.Get(fmt.Sprintf("workspaces/%s/projects/%s",
+		workspace,
+		url.PathEscape(strings.ToUpper(keyOrName)),
+	))
+
+	if projectReq == nil || projectReq.StatusCode != 404 {
+		if err != nil {
+			return "", err
+		}
+
+		var project Project
+		if decodeerr := json.NewDecoder(projectReq.Body).Decode(&project); decodeerr != nil || project.Key == "" {
+			return strings.ToUpper(keyOrName), nil
+		}
+		return project.Key, nil
+	}
 
+	values, err := client.GetPaged(fmt.Sprintf("workspaces/%s/projects", workspace))
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	var keys []string
+	for _, value := range values {
+		var project Project
+		if err := json.Unmarshal(value, &project); err != nil {
+			return "", err
+		}
+
+		if strings.EqualFold(project.Name, keyOrName) {
+			keys = append(keys, project.Key)
+		}
+	}
+
+	switch len(keys) {
+	case 0:
+		return "", fmt.Errorf("project %s not found in workspace %s", keyOrName, workspace)
+	case 1:
+		return keys[0], nil
+	default:
+		return "", fmt.Errorf("project name %s is ambiguous in workspace %s, it matches the projects %s, "+
+			"set project_key to one of their keys", keyOrName, workspace, strings.Join(keys, ", "))
+	}
+}
+
+// checkSlugCaseConflict explains a create that failed because the repository exists already when the
+// existing one only differs in case, slugs are unique regardless of case so bitbucket finds it under either
+func checkSlugCaseConflict(client *Client, owner, repoSlug string, createErr error) error {
+	apiErr, ok := createErr.(Error)
+	if !ok || apiErr.StatusCode != 400 || !strings.Contains(strings.ToLower(apiErr.APIError.Message), "already exists") {
+		return createErr
 	}
-	return resourceRepositoryRead(d, m)
+
+	existing, err := getRepository(client, owner, repoSlug)
+	if err != nil || existing.Slug == repoSlug || !strings.EqualFold(existing.Slug, repoSlug) {
+		return createErr
+	}
+
+	return fmt.Errorf("Repository %s/%s can't be created, slugs are unique regardless of case and the repository "+
+		"%s/%s already exists: %s", owner, repoSlug, owner, existing.Slug, createErr)
+}
+
+// configureRepository applies the settings that live on their own endpoints rather than on the repository itself
+func configureRepository(d *schema.ResourceData, client *Client, repoSlug string) error {
+	// Left unset a new repository keeps the pipelines setting it inherits from the workspace
+	_, pipelinesConfigured := d.GetOkExists("pipelines_enabled")
+	if d.HasChange("pipelines_enabled") || (d.IsNewResource() && pipelinesConfigured) {
+		pipelinesConfig := &PipelinesEnabled{Enabled: d.Get("pipelines_enabled").(bool)}
+
+		bytedata, err := json.Marshal(pipelinesConfig)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = client.Put(fmt.Sprintf("repositories/%s/%s/pipelines_config",
+			d.Get("owner").(string),
+			repoSlug), bytes.NewBuffer(bytedata))
+
+		if err != nil {
+			return fmt.Errorf("Failed to configure pipelines: %s", err)
+		}
+	}
+
+	if d.HasChange("main_branch") && d.Get("main_branch").(string) != "" {
+		if err := setRepositoryMainBranch(client, d.Get("owner").(string), repoSlug, d.Get("main_branch").(string)); err != nil {
+			return fmt.Errorf("Failed to configure the main branch: %s", err)
+		}
+	}
+
+	if d.HasChange("archived") {
+		if err := setRepositoryArchived(client, d.Get("owner").(string), repoSlug, d.Get("archived").(bool)); err != nil {
+			return fmt.Errorf("Failed to configure archiving: %s", err)
+		}
+	}
+
+	if d.HasChange("branch_restriction") {
+		if err := setRepositoryBranchRestrictions(d, client, repoSlug); err != nil {
+			return fmt.Errorf("Failed to configure branch restrictions: %s", err)
+		}
+	}
+
+	if d.HasChange("branching_model_settings") {
+		if err := setRepositoryBranchingModel(d, client, repoSlug); err != nil {
+			return fmt.Errorf("Failed to configure the branching model: %s", err)
+		}
+	}
+
+	if d.HasChange("webhook") {
+		if err := setRepositoryWebhook(d, client, repoSlug); err != nil {
+			return fmt.Errorf("Failed to configure the webhook: %s", err)
+		}
+	}
+
+	if d.HasChange("environment") {
+		if err := setRepositoryEnvironments(d, client, repoSlug); err != nil {
+			return fmt.Errorf("Failed to configure the deployment environments: %s", err)
+		}
+	}
+
+	return nil
 }
 
 func resourceRepositoryCreate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*Client)
 	repo := newRepositoryFromResource(d)
 
-	bytedata, err := json.Marshal(repo)
-
-	if err != nil {
-		return err
+	if repo.Project.Key != "" {
+		projectKey, err := resolveProjectKey(client, d.Get("owner").(string), repo.Project.Key)
+		if err != nil {
+			return err
+		}
+		repo.Project.Key = projectKey
 	}
 
 	var repoSlug string
@@ -204,35 +707,69 @@ func resourceRepositoryCreate(d *schema.ResourceData, m interface{}) error {
 		repoSlug = d.Get("name").(string)
 	}
 
-	_, err = client.Post(fmt.Sprintf("2.0/repositories/%s/%s",
-		d.Get("owner").(string),
-		repoSlug,
-	), bytes.NewBuffer(bytedata))
+	createURL := fmt.Sprintf("repositories/%s/%s", d.Get("owner").(string), repoSlug)
+	var payload interface{} = repo
+
+	// A fork is created from its parent, bitbucket picks the slug from the name
+	if forkOf := d.Get("fork_of").([]interface{}); len(forkOf) > 0 && forkOf[0] != nil {
+		parent := forkOf[0].(map[string]interface{})
+		createURL = fmt.Sprintf("repositories/%s/%s/forks", parent["owner"].(string), parent["slug"].(string))
+
+		fork := &RepositoryFork{Repository: repo}
+		fork.Workspace.Slug = d.Get("owner").(string)
+		payload = fork
+	}
+
+	bytedata, err := json.Marshal(payload)
 
 	if err != nil {
 		return err
 	}
+
+	repoReq, err := client.Post(createURL, bytes.NewBuffer(bytedata))
+
+	if err != nil {
+		return checkSlugCaseConflict(client, d.Get("owner").(string), repoSlug, err)
+	}
 	d.SetId(string(fmt.Sprintf("%s/%s", d.Get("owner").(string), repoSlug)))
 
-	var pipelinesEnabled bool
-	pipelinesEnabled = d.Get("pipelines_enabled").(bool)
-	pipelinesConfig := &PipelinesEnabled{Enabled: pipelinesEnabled}
+	var created Repository
 
-	bytedata, err = json.Marshal(pipelinesConfig)
+	decodeerr := json.NewDecoder(repoReq.Body).Decode(&created)
+	if decodeerr != nil {
+		return decodeerr
+	}
 
-	if err != nil {
+	if created.Slug != "" && created.Slug != repoSlug {
+		repoSlug = created.Slug
+		d.SetId(fmt.Sprintf("%s/%s", d.Get("owner").(string), repoSlug))
+	}
+
+	if err := checkPrivacyEnforced(d, repo.IsPrivate, created.IsPrivate); err != nil {
+		if readerr := resourceRepositoryRead(d, m); readerr != nil {
+			log.Printf("[WARN] Could not read repository %s: %s", d.Id(), readerr)
+		}
 		return err
 	}
 
-	_, err = client.Put(fmt.Sprintf("2.0/repositories/%s/%s/pipelines_config",
-		d.Get("owner").(string),
-		repoSlug), bytes.NewBuffer(bytedata))
+	if err := configureRepository(d, client, repoSlug); err != nil {
+		// Terraform taints a resource whose create failed, so record what actually got applied
+		// which lets an untainted repository be fixed up by a normal update on the next apply.
+		if readerr := resourceRepositoryRead(d, m); readerr != nil {
+			log.Printf("[WARN] Could not read repository %s after its configuration failed: %s", d.Id(), readerr)
+		}
 
-	if err != nil {
+		return fmt.Errorf("Repository %s was created but configuring it failed, it is now tainted and would be "+
+			"recreated on the next apply, run `terraform untaint` on it to keep the repository and only retry the "+
+			"configuration: %s", d.Id(), err)
+	}
+
+	if err := resourceRepositoryRead(d, m); err != nil {
 		return err
 	}
 
-	return resourceRepositoryRead(d, m)
+	checkFeaturesEnforced(d, repo)
+	return nil
 }
 func resourceRepositoryRead(d *schema.ResourceData, m interface{}) error {
 	id := d.Id()
@@ -253,10 +790,36 @@ func resourceRepositoryRead(d *schema.ResourceData, m interface{}) error {
 	}
 
 	client := m.(*Client)
-	repoReq, _ := client.Get(fmt.Sprintf("2.0/repositories/%s/%s",
-		d.Get("owner").(string),
-		repoSlug,
-	))
+	repoReq, err := client.Get(repositoryReadURL(d.Get("owner").(string), repoSlug))
+
+	// The slug changes when a repository is renamed outside of terraform, follow it by uuid
+	if repoReq != nil && repoReq.StatusCode == 404 && d.Get("uuid").(string) != "" {
+		renamedSlug, finderr := findRepositorySlugByUUID(client, d.Get("owner").(string), d.Get("uuid").(string))
+		if finderr != nil {
+			return finderr
+		}
+
+		if renamedSlug != "" {
+			log.Printf("[INFO] Repository %s was renamed to %s", d.Id(), renamedSlug)
+
+			repoSlug = renamedSlug
+			d.SetId(fmt.Sprintf("%s/%s", d.Get("owner").(string), repoSlug))
+			d.Set("slug", repoSlug)
+
+			repoReq, err = client.Get(repositoryReadURL(d.Get("owner").(string), repoSlug))
+		}
+	}
+
+	// Deleted outside of terraform, clearing the ID makes the plan create it again
+	if repoReq != nil && repoReq.StatusCode == 404 {
+		log.Printf("[WARN] Repository %s not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
 
 	if repoReq.StatusCode == 200 {
 
@@ -272,6 +835,25 @@ func resourceRepositoryRead(d *schema.ResourceData, m interface{}) error {
 			return decodeerr
 		}
 
+		// A response for another repository, e.g. misrouted around a rename, must not end up in state
+		for _, cloneURL := range repo.Links.Clone {
+			if cloneURL.Name == "https" && !cloneURLMatches(cloneURL.Href, d.Get("owner").(string), repoSlug) {
+				log.Printf("[WARN] Repository %s answered with the clone URL %s of another repository, removing from state",
+					d.Id(), cloneURL.Href)
+				d.SetId("")
+				return nil
+			}
+		}
+
+		// The slug in the ID comes from the config, bitbucket answers for any casing of it but
+		// only the canonical slug it returns is safe to build the other urls with
+		if repo.Slug != "" && repo.Slug != repoSlug {
+			repoSlug = repo.Slug
+			d.SetId(fmt.Sprintf("%s/%s", d.Get("owner").(string), repoSlug))
+			d.Set("slug", repoSlug)
+		}
+
+		d.Set("uuid", repo.UUID)
 		d.Set("scm", repo.SCM)
 		d.Set("is_private", repo.IsPrivate)
 		d.Set("has_wiki", repo.HasWiki)
@@ -285,6 +867,26 @@ func resourceRepositoryRead(d *schema.ResourceData, m interface{}) error {
 		d.Set("website", repo.Website)
 		d.Set("description", repo.Description)
 		d.Set("project_key", repo.Project.Key)
+		d.Set("project_name", repo.Project.Name)
+		if repo.Parent != nil {
+			d.Set("parent_full_name", repo.Parent.FullName)
+			d.Set("fork_of", flattenForkOf(d, repo.Parent.FullName))
+		} else {
+			d.Set("parent_full_name", "")
+			d.Set("fork_of", nil)
+		}
+
+		// Empty repositories have no main branch until the first push
+		if repo.Mainbranch != nil {
+			d.Set("main_branch", repo.Mainbranch.Name)
+		} else {
+			d.Set("main_branch", "")
+		}
+
+		d.Set("size", repo.Size)
+		d.Set("size_over_threshold", repositorySizeWarning(d.Id(), d.Get("size").(int), d.Get("size_warn_threshold").(int)) != "")
+		d.Set("created_on", formatTimestamp(repo.CreatedOn))
+		d.Set("updated_on", formatTimestamp(repo.UpdatedOn))
 
 		for _, cloneURL := range repo.Links.Clone {
 			if cloneURL.Name == "https" {
@@ -293,7 +895,18 @@ func resourceRepositoryRead(d *schema.ResourceData, m interface{}) error {
 				d.Set("clone_ssh", cloneURL.Href)
 			}
 		}
-		pipelinesConfigReq, err := client.Get(fmt.Sprintf("2.0/repositories/%s/%s/pipelines_config",
+
+		d.Set("avatar_url", repo.Links.Avatar.Href)
+
+		// The wiki is a separate git repository living under the repository clone URL
+		if repo.HasWiki {
+			d.Set("wiki_clone_https", wikiCloneURL(d.Get("clone_https").(string)))
+			d.Set("wiki_clone_ssh", wikiCloneURL(d.Get("clone_ssh").(string)))
+		} else {
+			d.Set("wiki_clone_https", "")
+			d.Set("wiki_clone_ssh", "")
+		}
+		pipelinesConfigReq, err := client.Get(fmt.Sprintf("repositories/%s/%s/pipelines_config",
 			d.Get("owner").(string),
 			repoSlug))
 
@@ -317,11 +930,446 @@ func resourceRepositoryRead(d *schema.ResourceData, m interface{}) error {
 			d.Set("pipelines_enabled", pipelinesConfig.Enabled)
 		}
 
+		archiveRestrictionID, err := findArchiveRestriction(client, d.Get("owner").(string), repoSlug)
+		if err != nil {
+			return err
+		}
+
+		d.Set("archived", archiveRestrictionID != 0)
+
+		if err := readRepositoryBranchRestrictions(d, client, repoSlug); err != nil {
+			return err
+		}
+
+		if err := readRepositoryBranchingModel(d, client, repoSlug); err != nil {
+			return err
+		}
+
+		if err := readRepositoryWebhook(d, client, repoSlug); err != nil {
+			return err
+		}
+
+		if err := readRepositoryEnvironments(d, client, repoSlug); err != nil {
+			return err
+		}
+
+		if err := readRepositoryIssueTracker(d, client, repoSlug); err != nil {
+			return err
+		}
+
+		readRepositoryDependents(d, client, repoSlug)
+
+	}
+
+	return nil
+}
+
+// repositoryReadFields are the fields of a repository the read uses, asking for just these leaves
+// out the owner, the many links and the rest of what bitbucket sends by default
+var repositoryReadFields = []string{
+	"uuid",
+	"name",
+	"slug",
+	"scm",
+	"is_private",
+	"has_wiki",
+	"has_issues",
+	"language",
+	"fork_policy",
+	"website",
+	"description",
+	"project.key",
+	"project.name",
+	"mainbranch.name",
+	"size",
+	"created_on",
+	"updated_on",
+	"links.clone",
+	"links.avatar",
+	"parent.full_name",
+}
+
+// flattenForkOf turns the full name of the parent into fork_of, keeping the casing of the config
+// since bitbucket matches owners and slugs regardless of it
+func flattenForkOf(d *schema.ResourceData, parentFullName string) []map[string]interface{} {
+	parts := strings.SplitN(parentFullName, "/", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+
+	if forkOf := d.Get("fork_of").([]interface{}); len(forkOf) > 0 && forkOf[0] != nil {
+		configured := forkOf[0].(map[string]interface{})
+		if strings.EqualFold(configured["owner"].(string)+"/"+configured["slug"].(string), parentFullName) {
+			return []map[string]interface{}{configured}
+		}
+	}
+
+	return []map[string]interface{}{{"owner": parts[0], "slug": parts[1]}}
+}
+
+func repositoryReadURL(owner, repoSlug string) string {
+	return fmt.Sprintf("repositories/%s/%s?fields=%s",
+		owner,
+		repoSlug,
+		url.QueryEscape(strings.Join(repositoryReadFields, ",")),
+	)
+}
+
+// findRepositorySlugByUUID looks for a repository in the workspace by its uuid, which survives
+// renames, and returns its current slug or an empty string when it is gone
+func findRepositorySlugByUUID(client *Client, owner, uuid string) (string, error) {
+	repositoriesReq, err := client.Get(fmt.Sprintf("repositories/%s?q=%s",
+		owner,
+		url.QueryEscape(fmt.Sprintf(`uuid="%s"`, uuid)),
+	))
+
+	if err != nil {
+		return "", err
+	}
+
+	var repositories PaginatedRepositories
+
+	decodeerr := json.NewDecoder(repositoriesReq.Body).Decode(&repositories)
+	if decodeerr != nil {
+		return "", decodeerr
+	}
+
+	for _, repo := range repositories.Values {
+		if repo.UUID == uuid {
+			return repo.Slug, nil
+		}
+	}
+
+	return "", nil
+}
+
+// cloneURLMatches checks the https clone URL is the one of owner/repoSlug. Casing is ignored like
+// bitbucket does, and an owner given by uuid only has its slug checked.
+func cloneURLMatches(cloneURL, owner, repoSlug string) bool {
+	parsed, err := url.Parse(cloneURL)
+	if err != nil || parsed.Path == "" {
+		return true
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.Trim(parsed.Path, "/"), ".git"), "/")
+	if len(parts) != 2 {
+		return false
+	}
+
+	if !strings.HasPrefix(owner, "{") && !strings.EqualFold(parts[0], owner) {
+		return false
+	}
+	return strings.EqualFold(parts[1], repoSlug)
+}
+
+func wikiCloneURL(cloneURL string) string {
+	if cloneURL == "" {
+		return ""
+	}
+	return cloneURL + "/wiki"
+}
+
+// findArchiveRestriction returns the ID of the branch restriction that stops everybody pushing to
+// any branch, which is how an archived repository is made read only, or 0 when there isn't one
+func findArchiveRestriction(client *Client, owner, repoSlug string) (int, error) {
+	values, err := client.GetPaged(fmt.Sprintf("repositories/%s/%s/branch-restrictions?kind=push&pattern=%s",
+		owner,
+		repoSlug,
+		url.QueryEscape("*"),
+	))
+
+	if err != nil {
+		return 0, err
+	}
+
+	for _, value := range values {
+		var restriction BranchRestriction
+
+		decodeerr := json.Unmarshal(value, &restriction)
+		if decodeerr != nil {
+			return 0, decodeerr
+		}
+
+		if restriction.Kind == "push" && restriction.Pattern == "*" &&
+			len(restriction.Users) == 0 && len(restriction.Groups) == 0 {
+			return restriction.ID, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// setRepositoryArchived adds or removes the branch restriction that makes a repository read only
+func setRepositoryArchived(client *Client, owner, repoSlug string, archived bool) error {
+	archiveRestrictionID, err := findArchiveRestriction(client, owner, repoSlug)
+	if err != nil {
+		return err
+	}
+
+	if archived && archiveRestrictionID == 0 {
+		bytedata, err := json.Marshal(&BranchRestriction{Kind: "push", Pattern: "*"})
+		if err != nil {
+			return err
+		}
+
+		_, err = client.Post(fmt.Sprintf("repositories/%s/%s/branch-restrictions",
+			owner,
+			repoSlug,
+		), bytes.NewBuffer(bytedata))
+
+		return err
+	}
+
+	if !archived && archiveRestrictionID != 0 {
+		_, err = client.Delete(fmt.Sprintf("repositories/%s/%s/branch-restrictions/%d",
+			owner,
+			repoSlug,
+			archiveRestrictionID,
+		))
+
+		return err
+	}
+
+	return nil
+}
+
+// setRepositoryBranchRestrictions replaces the inline branch restrictions, the old ones are
+// removed by id and every block is created again so the ids in state match bitbucket
+func setRepositoryBranchRestrictions(d *schema.ResourceData, client *Client, repoSlug string) error {
+	owner := d.Get("owner").(string)
+	old, new := d.GetChange("branch_restriction")
+
+	for _, item := range old.([]interface{}) {
+		id := item.(map[string]interface{})["id"].(int)
+		if id == 0 {
+			continue
+		}
+
+		// Somebody else already removed it
+		err := client.DeleteIgnoringNotFound(fmt.Sprintf("repositories/%s/%s/branch-restrictions/%d",
+			owner,
+			repoSlug,
+			id,
+		))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	restrictions := make([]map[string]interface{}, 0, len(new.([]interface{})))
+
+	for _, item := range new.([]interface{}) {
+		m := item.(map[string]interface{})
+		branchRestriction := expandBranchRestriction(m)
+
+		if err := validateBranchRestrictionValue(branchRestriction.Kind, branchRestriction.Value); err != nil {
+			return err
+		}
+
+		bytedata, err := json.Marshal(branchRestriction)
+		if err != nil {
+			return err
+		}
+
+		branchRestrictionReq, err := client.Post(fmt.Sprintf("repositories/%s/%s/branch-restrictions",
+			owner,
+			repoSlug,
+		), bytes.NewBuffer(bytedata))
+
+		if err != nil {
+			return err
+		}
+
+		var created BranchRestriction
+
+		decodeerr := json.NewDecoder(branchRestrictionReq.Body).Decode(&created)
+		if decodeerr != nil {
+			return decodeerr
+		}
+
+		restrictions = append(restrictions, map[string]interface{}{
+			"id":      created.ID,
+			"kind":    branchRestriction.Kind,
+			"pattern": branchRestriction.Pattern,
+			"value":   branchRestriction.Value,
+			"users":   m["users"],
+			"groups":  m["groups"],
+		})
+	}
+
+	d.Set("branch_restriction", restrictions)
+
+	return nil
+}
+
+// readRepositoryBranchRestrictions refreshes the inline branch restrictions we created,
+// restrictions added outside of terraform are left alone
+func readRepositoryBranchRestrictions(d *schema.ResourceData, client *Client, repoSlug string) error {
+	inState := d.Get("branch_restriction").([]interface{})
+	if len(inState) == 0 {
+		return nil
+	}
+
+	restrictions := make([]map[string]interface{}, 0, len(inState))
+
+	for _, item := range inState {
+		id := item.(map[string]interface{})["id"].(int)
+
+		branchRestrictionReq, err := client.Get(fmt.Sprintf("repositories/%s/%s/branch-restrictions/%d",
+			d.Get("owner").(string),
+			repoSlug,
+			id,
+		))
+
+		// Removed outside of terraform, dropping it makes the plan create it again
+		if branchRestrictionReq != nil && branchRestrictionReq.StatusCode == 404 {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var branchRestriction BranchRestriction
+
+		decodeerr := json.NewDecoder(branchRestrictionReq.Body).Decode(&branchRestriction)
+		if decodeerr != nil {
+			return decodeerr
+		}
+
+		restrictions = append(restrictions, map[string]interface{}{
+			"id":      branchRestriction.ID,
+			"kind":    branchRestriction.Kind,
+			"pattern": branchRestriction.Pattern,
+			"value":   branchRestriction.Value,
+			"users":   flattenBranchRestrictionUsers(branchRestriction.Users),
+			"groups":  flattenBranchRestrictionGroups(branchRestriction.Groups),
+		})
 	}
 
+	d.Set("branch_restriction", restrictions)
+
 	return nil
 }
 
+// formatTimestamp turns the timestamps bitbucket returns into RFC3339 in UTC, anything that
+// can't be parsed is passed through as is rather than dropped
+func formatTimestamp(timestamp string) string {
+	if timestamp == "" {
+		return ""
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		log.Printf("[WARN] Could not parse timestamp %q: %s", timestamp, err)
+		return timestamp
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// getRepository reads the repository as bitbucket has it now, e.g. to find its canonical slug or
+// main branch outside of the read
+func getRepository(client *Client, owner, repoSlug string) (*Repository, error) {
+	repoReq, err := client.Get(fmt.Sprintf("repositories/%s/%s",
+		owner,
+		repoSlug,
+	))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var repo Repository
+
+	decodeerr := json.NewDecoder(repoReq.Body).Decode(&repo)
+	if decodeerr != nil {
+		return nil, decodeerr
+	}
+
+	return &repo, nil
+}
+
+// countRepositoryItems counts what is in a collection of the repository, e.g. its hooks, by asking
+// for a single item and using the size of the page. A collection the repository doesn't have is empty.
+func countRepositoryItems(client *Client, owner, repoSlug, collection string) (int, error) {
+	separator := "?"
+	if strings.Contains(collection, "?") {
+		separator = "&"
+	}
+
+	itemsReq, err := client.Get(fmt.Sprintf("repositories/%s/%s/%s%spagelen=1", owner, repoSlug, collection, separator))
+
+	if itemsReq != nil && itemsReq.StatusCode == 404 {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	var page struct {
+		Size int `json:"size"`
+	}
+
+	decodeerr := json.NewDecoder(itemsReq.Body).Decode(&page)
+	if decodeerr != nil {
+		return 0, decodeerr
+	}
+
+	return page.Size, nil
+}
+
+// getRepositoryMainBranch looks up the name of the main branch of a repository
+func getRepositoryMainBranch(client *Client, owner, repoSlug string) (string, error) {
+	repo, err := getRepository(client, owner, repoSlug)
+	if err != nil {
+		return "", err
+	}
+
+	if repo.Mainbranch == nil {
+		return "", noBranchesError(owner, repoSlug)
+	}
+
+	return repo.Mainbranch.Name, nil
+}
+
+func noBranchesError(owner, repoSlug string) error {
+	return fmt.Errorf("Repository %s/%s has no branches yet, push a commit before relying on its main branch", owner, repoSlug)
+}
+
+// setRepositoryMainBranch makes an existing branch the main branch of a repository, an empty
+// repository has no branches to choose from yet
+func setRepositoryMainBranch(client *Client, owner, repoSlug, branch string) error {
+	repo, err := getRepository(client, owner, repoSlug)
+	if err != nil {
+		return err
+	}
+
+	if repo.Mainbranch == nil {
+		return noBranchesError(owner, repoSlug)
+	}
+
+	if repo.Mainbranch.Name == branch {
+		return nil
+	}
+
+	bytedata, err := json.Marshal(map[string]interface{}{
+		"mainbranch": &MainBranch{Name: branch},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Put(fmt.Sprintf("repositories/%s/%s",
+		owner,
+		repoSlug,
+	), bytes.NewBuffer(bytedata))
+
+	return err
+}
+
 func resourceRepositoryDelete(d *schema.ResourceData, m interface{}) error {
 
 	var repoSlug string
@@ -331,10 +1379,70 @@ func resourceRepositoryDelete(d *schema.ResourceData, m interface{}) error {
 	}
 
 	client := m.(*Client)
-	_, err := client.Delete(fmt.Sprintf("2.0/repositories/%s/%s",
+
+	if d.Get("warn_on_destroy").(bool) {
+		if warning := repositoryDependentsWarning(client, d.Get("owner").(string), repoSlug); warning != "" {
+			log.Printf("[WARN] %s", warning)
+		}
+	}
+
+	_, err := client.Delete(fmt.Sprintf("repositories/%s/%s",
 		d.Get("owner").(string),
 		repoSlug,
 	))
 
 	return err
 }
+
+// repositoryDependents are the collections lost with a repository that warn_on_destroy counts
+var repositoryDependents = []struct {
+	Collection string
+	Name       string
+	Attribute  string
+}{
+	{"pullrequests?state=OPEN", "open pull requests", "open_pull_requests"},
+	{"deploy-keys", "deploy keys", "deploy_key_count"},
+	{"hooks", "webhooks", "webhook_count"},
+}
+
+// readRepositoryDependents counts what goes away with the repository when warn_on_destroy is set, so
+// plan and show have the counts before a destroy is approved. A count that can't be read is left as is.
+func readRepositoryDependents(d *schema.ResourceData, client *Client, repoSlug string) {
+	for _, dependent := range repositoryDependents {
+		if !d.Get("warn_on_destroy").(bool) {
+			d.Set(dependent.Attribute, 0)
+			continue
+		}
+
+		count, err := countRepositoryItems(client, d.Get("owner").(string), repoSlug, dependent.Collection)
+		if err != nil {
+			log.Printf("[WARN] Could not count the %s of repository %s: %s", dependent.Name, d.Id(), err)
+			continue
+		}
+
+		d.Set(dependent.Attribute, count)
+	}
+}
+
+// repositoryDependentsWarning says what goes away with the repository. It never stops the delete, a
+// count that can't be read is left out of the warning.
+func repositoryDependentsWarning(client *Client, owner, repoSlug string) string {
+	var lost []string
+
+	for _, dependent := range repositoryDependents {
+		count, err := countRepositoryItems(client, owner, repoSlug, dependent.Collection)
+		if err != nil {
+			log.Printf("[WARN] Could not count the %s of repository %s/%s: %s", dependent.Name, owner, repoSlug, err)
+			continue
+		}
+
+		if count > 0 {
+			lost = append(lost, fmt.Sprintf("%d %s", count, dependent.Name))
+		}
+	}
+
+	if len(lost) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Deleting repository %s/%s also deletes its %s", owner, repoSlug, strings.Join(lost, ", "))
+}
@@ -0,0 +1,262 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+func resourceRepositoryBranchRestrictions() *schema.Resource {
+	return &schema.Resource{
+		Create:        resourceRepositoryBranchRestrictionsCreate,
+		Read:          resourceRepositoryBranchRestrictionsRead,
+		Update:        resourceRepositoryBranchRestrictionsUpdate,
+		Delete:        resourceRepositoryBranchRestrictionsDelete,
+		CustomizeDiff: resourceRepositoryBranchRestrictionsCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"owner": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"repository": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"ignore_unmanaged": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"pattern_prefix": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"branch_restriction": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"kind": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.StringInSlice(branchRestrictionKinds, false),
+						},
+						"pattern": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"users": {
+							Type:     schema.TypeSet,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+							Optional: true,
+							Set:      schema.HashString,
+						},
+						"groups": {
+							Type:     schema.TypeSet,
+							Elem:     branchRestrictionGroupResource(),
+							Optional: true,
+						},
+						"value": {
+							Type:     schema.TypeInt,
+							Optional: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceRepositoryBranchRestrictionsCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	restrictions := expandBranchRestrictions(d.Get("branch_restriction"))
+
+	// Interpolated values read as 0 until apply, they are checked once they are known
+	if d.NewValueKnown("branch_restriction") {
+		for _, restriction := range restrictions {
+			if err := validateBranchRestrictionValue(restriction.Kind, restriction.Value); err != nil {
+				return err
+			}
+		}
+	}
+
+	prefix, err := managedPrefix(d, "ignore_unmanaged", "pattern_prefix")
+	if err != nil || prefix == "" {
+		return err
+	}
+
+	for _, restriction := range restrictions {
+		if restriction.Pattern != "" && !strings.HasPrefix(restriction.Pattern, prefix) {
+			return fmt.Errorf("branch_restriction %s on %q must have a pattern starting with %q when ignore_unmanaged is true",
+				restriction.Kind, restriction.Pattern, prefix)
+		}
+	}
+
+	return nil
+}
+
+func expandBranchRestrictions(v interface{}) []*BranchRestriction {
+	restrictions := make([]*BranchRestriction, 0, len(v.(*schema.Set).List()))
+
+	for _, item := range v.(*schema.Set).List() {
+		restrictions = append(restrictions, expandBranchRestriction(item.(map[string]interface{})))
+	}
+
+	return restrictions
+}
+
+func flattenBranchRestrictions(restrictions []*BranchRestriction) []map[string]interface{} {
+	flattened := make([]map[string]interface{}, 0, len(restrictions))
+
+	for _, restriction := range restrictions {
+		flattened = append(flattened, map[string]interface{}{
+			"kind":    restriction.Kind,
+			"pattern": restriction.Pattern,
+			"value":   restriction.Value,
+			"users":   flattenBranchRestrictionUsers(restriction.Users),
+			"groups":  flattenBranchRestrictionGroups(restriction.Groups),
+		})
+	}
+
+	return flattened
+}
+
+// branchRestrictionKey identifies a branch restriction by everything we manage about it, one that
+// differs in any way is replaced rather than updated
+func branchRestrictionKey(restriction *BranchRestriction) string {
+	return fmt.Sprintf("%s|%s|%d|%s|%s",
+		restriction.Kind,
+		restriction.Pattern,
+		restriction.Value,
+		branchRestrictionUsersString(restriction.Users),
+		branchRestrictionGroupsString(restriction.Groups),
+	)
+}
+
+// getManagedBranchRestrictions returns the branch restrictions on the repository this resource owns,
+// that is all of them unless ignore_unmanaged limits it to the patterns with the prefix
+func getManagedBranchRestrictions(d *schema.ResourceData, client *Client) ([]*BranchRestriction, error) {
+	values, err := getRepositoryCollection(client, d.Get("owner").(string), d.Get("repository").(string),
+		"branch-restrictions")
+	if err != nil {
+		return nil, err
+	}
+
+	managed := make([]*BranchRestriction, 0, len(values))
+	for _, value := range values {
+		var restriction BranchRestriction
+		if err := json.Unmarshal(value, &restriction); err != nil {
+			return nil, err
+		}
+
+		if isManaged(d, "ignore_unmanaged", "pattern_prefix", restriction.Pattern) {
+			managed = append(managed, &restriction)
+		}
+	}
+
+	return managed, nil
+}
+
+func branchRestrictionKeys(restrictions []*BranchRestriction) []string {
+	keys := make([]string, 0, len(restrictions))
+	for _, restriction := range restrictions {
+		keys = append(keys, branchRestrictionKey(restriction))
+	}
+
+	return keys
+}
+
+// reconcileBranchRestrictions deletes every managed branch restriction that isn't in the config by
+// its id and creates the missing ones
+func reconcileBranchRestrictions(d *schema.ResourceData, client *Client, desired []*BranchRestriction) error {
+	owner := d.Get("owner").(string)
+	repoSlug := d.Get("repository").(string)
+
+	existing, err := getManagedBranchRestrictions(d, client)
+	if err != nil {
+		return err
+	}
+
+	remove := func(i int) error {
+		restriction := existing[i]
+		err := client.DeleteIgnoringNotFound(fmt.Sprintf("repositories/%s/%s/branch-restrictions/%d",
+			owner,
+			repoSlug,
+			restriction.ID,
+		))
+
+		if err != nil {
+			return fmt.Errorf("Failed to delete %s branch restriction %d on %s: %s", restriction.Kind, restriction.ID,
+				restriction.Pattern, err)
+		}
+
+		return nil
+	}
+
+	create := func(i int) error {
+		restriction := desired[i]
+		payload, err := json.Marshal(restriction)
+		if err != nil {
+			return err
+		}
+
+		_, err = client.Post(fmt.Sprintf("repositories/%s/%s/branch-restrictions",
+			owner,
+			repoSlug,
+		), bytes.NewBuffer(payload))
+
+		if err != nil {
+			return fmt.Errorf("Failed to create %s branch restriction on %s: %s", restriction.Kind, restriction.Pattern, err)
+		}
+
+		return nil
+	}
+
+	return reconcile(branchRestrictionKeys(existing), branchRestrictionKeys(desired), remove, create)
+}
+
+func resourceRepositoryBranchRestrictionsCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	if err := reconcileBranchRestrictions(d, client, expandBranchRestrictions(d.Get("branch_restriction"))); err != nil {
+		return err
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", d.Get("owner").(string), d.Get("repository").(string)))
+
+	return resourceRepositoryBranchRestrictionsRead(d, m)
+}
+
+func resourceRepositoryBranchRestrictionsRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	restrictions, err := getManagedBranchRestrictions(d, client)
+	if err != nil {
+		return err
+	}
+
+	d.Set("branch_restriction", flattenBranchRestrictions(restrictions))
+
+	return nil
+}
+
+func resourceRepositoryBranchRestrictionsUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	if err := reconcileBranchRestrictions(d, client, expandBranchRestrictions(d.Get("branch_restriction"))); err != nil {
+		return err
+	}
+
+	return resourceRepositoryBranchRestrictionsRead(d, m)
+}
+
+func resourceRepositoryBranchRestrictionsDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+	return reconcileBranchRestrictions(d, client, nil)
+}
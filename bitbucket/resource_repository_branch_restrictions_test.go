@@ -0,0 +1,183 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// testBranchRestrictionsServer keeps branch restrictions in memory so reconciling can be checked end to end
+type testBranchRestrictionsServer struct {
+	restrictions map[int]BranchRestriction
+	nextID       int
+}
+
+func (s *testBranchRestrictionsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	restrictionsPath := "/2.0/repositories/test-owner/test-repo/branch-restrictions"
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == restrictionsPath:
+		var page PaginatedBranchRestrictions
+		for _, restriction := range s.restrictions {
+			page.Values = append(page.Values, restriction)
+		}
+		json.NewEncoder(w).Encode(page)
+	case r.Method == "POST" && r.URL.Path == restrictionsPath:
+		var restriction BranchRestriction
+		json.NewDecoder(r.Body).Decode(&restriction)
+		s.nextID++
+		restriction.ID = s.nextID
+		s.restrictions[restriction.ID] = restriction
+		json.NewEncoder(w).Encode(restriction)
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, restrictionsPath+"/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, restrictionsPath+"/"))
+		delete(s.restrictions, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"type": "error", "error": {"message": "Not found"}}`))
+	}
+}
+
+func (s *testBranchRestrictionsServer) patterns() []string {
+	var patterns []string
+	for _, restriction := range s.restrictions {
+		patterns = append(patterns, restriction.Kind+" "+restriction.Pattern)
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+func TestRepositoryBranchRestrictions_reconcile(t *testing.T) {
+	cases := map[string]struct {
+		IgnoreUnmanaged bool
+		Expected        []string
+	}{
+		"enforce deletes every unmanaged restriction": {
+			Expected: []string{"force release/*", "push release/*"},
+		},
+		"ignore_unmanaged only manages the prefix": {
+			IgnoreUnmanaged: true,
+			Expected:        []string{"delete master", "force release/*", "push release/*"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := &testBranchRestrictionsServer{
+				nextID: 3,
+				restrictions: map[int]BranchRestriction{
+					1: {ID: 1, Kind: "push", Pattern: "release/*", Users: []User{{Username: "alice"}}},
+					2: {ID: 2, Kind: "delete", Pattern: "master"},
+					3: {ID: 3, Kind: "push", Pattern: "release/old"},
+				},
+			}
+
+			client, closeServer := testClient(t, server)
+			defer closeServer()
+
+			d := schema.TestResourceDataRaw(t, resourceRepositoryBranchRestrictions().Schema, map[string]interface{}{
+				"owner":            "test-owner",
+				"repository":       "test-repo",
+				"ignore_unmanaged": tc.IgnoreUnmanaged,
+				"pattern_prefix":   "release/",
+				"branch_restriction": []interface{}{
+					map[string]interface{}{
+						"kind":    "push",
+						"pattern": "release/*",
+						"users":   []interface{}{"alice"},
+					},
+					map[string]interface{}{
+						"kind":    "force",
+						"pattern": "release/*",
+					},
+				},
+			})
+
+			if err := resourceRepositoryBranchRestrictionsCreate(d, client); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if patterns := server.patterns(); strings.Join(patterns, ",") != strings.Join(tc.Expected, ",") {
+				t.Fatalf("expected restrictions %v, got %v", tc.Expected, patterns)
+			}
+
+			if _, ok := server.restrictions[1]; !ok {
+				t.Fatal("expected the unchanged restriction to be kept rather than recreated")
+			}
+
+			if n := d.Get("branch_restriction").(*schema.Set).Len(); n != 2 {
+				t.Fatalf("expected 2 managed restrictions in state, got %d", n)
+			}
+
+			// Added in the UI, the read picks it up so the plan shows it going away
+			server.restrictions[10] = BranchRestriction{ID: 10, Kind: "delete", Pattern: "release/*"}
+
+			if err := resourceRepositoryBranchRestrictionsRead(d, client); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if n := d.Get("branch_restriction").(*schema.Set).Len(); n != 3 {
+				t.Fatalf("expected the restriction added outside of terraform in state, got %d", n)
+			}
+
+			if err := resourceRepositoryBranchRestrictionsDelete(d, client); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			remaining := server.patterns()
+			if tc.IgnoreUnmanaged && strings.Join(remaining, ",") != "delete master" {
+				t.Fatalf("expected the unmanaged restriction to survive delete, got %v", remaining)
+			}
+			if !tc.IgnoreUnmanaged && len(remaining) != 0 {
+				t.Fatalf("expected every restriction to be deleted, got %v", remaining)
+			}
+		})
+	}
+}
+
+func TestRepositoryBranchRestrictions_customizeDiff(t *testing.T) {
+	cases := map[string]struct {
+		Config map[string]interface{}
+		Error  string
+	}{
+		"prefix required": {
+			Config: map[string]interface{}{"ignore_unmanaged": true},
+			Error:  "pattern_prefix must be set when ignore_unmanaged is true",
+		},
+		"pattern outside the prefix": {
+			Config: map[string]interface{}{
+				"ignore_unmanaged": true,
+				"pattern_prefix":   "release/",
+				"branch_restriction": []interface{}{
+					map[string]interface{}{"kind": "push", "pattern": "master"},
+				},
+			},
+			Error: `branch_restriction push on "master" must have a pattern starting with "release/"`,
+		},
+		"value not used by the kind": {
+			Config: map[string]interface{}{
+				"branch_restriction": []interface{}{
+					map[string]interface{}{"kind": "push", "pattern": "master", "value": 2},
+				},
+			},
+			Error: "value can't be set when kind is push",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tc.Config["owner"] = "test-owner"
+			tc.Config["repository"] = "test-repo"
+
+			_, err := resourceRepositoryBranchRestrictions().Diff(nil, testResourceConfig(t, tc.Config), nil)
+			if err == nil || !strings.Contains(err.Error(), tc.Error) {
+				t.Fatalf("expected error containing %q, got %v", tc.Error, err)
+			}
+		})
+	}
+}
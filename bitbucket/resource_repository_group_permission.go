@@ -0,0 +1,133 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+func resourceRepositoryGroupPermission() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceRepositoryGroupPermissionPut,
+		Read:   resourceRepositoryGroupPermissionRead,
+		Update: resourceRepositoryGroupPermissionPut,
+		Delete: resourceRepositoryGroupPermissionDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"workspace": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"repo_slug": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"group_slug": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"permission": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice([]string{"read", "write", "admin"}, false),
+			},
+		},
+	}
+}
+
+func repositoryGroupPermissionURL(workspace, repoSlug, groupSlug string) string {
+	return fmt.Sprintf("repositories/%s/%s/permissions-config/groups/%s",
+		workspace,
+		repoSlug,
+		url.PathEscape(groupSlug),
+	)
+}
+
+// resourceRepositoryGroupPermissionPut is used for create and update, bitbucket has one PUT that
+// gives a group access and changes the access it has
+func resourceRepositoryGroupPermissionPut(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	bytedata, err := json.Marshal(map[string]interface{}{
+		"permission": d.Get("permission").(string),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Put(repositoryGroupPermissionURL(
+		d.Get("workspace").(string),
+		d.Get("repo_slug").(string),
+		d.Get("group_slug").(string),
+	), bytes.NewBuffer(bytedata))
+
+	if err != nil {
+		return fmt.Errorf("Failed to give group %s %s permission on %s/%s: %s", d.Get("group_slug").(string),
+			d.Get("permission").(string), d.Get("workspace").(string), d.Get("repo_slug").(string), err)
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s/%s",
+		d.Get("workspace").(string),
+		d.Get("repo_slug").(string),
+		d.Get("group_slug").(string),
+	))
+
+	return resourceRepositoryGroupPermissionRead(d, m)
+}
+
+func resourceRepositoryGroupPermissionRead(d *schema.ResourceData, m interface{}) error {
+	idparts := strings.SplitN(d.Id(), "/", 3)
+	if len(idparts) != 3 {
+		return fmt.Errorf("Incorrect ID format, should match `workspace/repo_slug/group_slug`")
+	}
+
+	d.Set("workspace", idparts[0])
+	d.Set("repo_slug", idparts[1])
+	d.Set("group_slug", idparts[2])
+
+	client := m.(*Client)
+	permissionReq, err := client.Get(repositoryGroupPermissionURL(idparts[0], idparts[1], idparts[2]))
+
+	// The group lost its access outside of terraform, so it is given again
+	if permissionReq != nil && permissionReq.StatusCode == 404 {
+		log.Printf("[WARN] Repository group permission %s not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var permission GroupPermission
+
+	decodeerr := json.NewDecoder(permissionReq.Body).Decode(&permission)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	d.Set("permission", permission.Permission)
+
+	return nil
+}
+
+func resourceRepositoryGroupPermissionDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+	return client.DeleteIgnoringNotFound(repositoryGroupPermissionURL(
+		d.Get("workspace").(string),
+		d.Get("repo_slug").(string),
+		d.Get("group_slug").(string),
+	))
+}
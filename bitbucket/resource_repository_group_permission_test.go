@@ -0,0 +1,140 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestRepositoryGroupPermission_lifecycle(t *testing.T) {
+	permission := ""
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2.0/repositories/test-owner/test-repo/permissions-config/groups/developers" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		switch r.Method {
+		case "PUT":
+			var sent GroupPermission
+			json.NewDecoder(r.Body).Decode(&sent)
+			permission = sent.Permission
+		case "DELETE":
+			permission = ""
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if permission == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"type": "repository_group_permission", "permission": "` + permission + `", "group": {"slug": "developers"}}`))
+	}))
+	defer closeServer()
+
+	raw := func(permission string) map[string]interface{} {
+		return map[string]interface{}{
+			"workspace":  "test-owner",
+			"repo_slug":  "test-repo",
+			"group_slug": "developers",
+			"permission": permission,
+		}
+	}
+
+	r := resourceRepositoryGroupPermission()
+	var state *terraform.InstanceState
+
+	for _, level := range []string{"write", "admin"} {
+		diff, err := r.Diff(state, testResourceConfig(t, raw(level)), client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if state, err = r.Apply(state, diff, client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if permission != level || state.ID != "test-owner/test-repo/developers" || state.Attributes["permission"] != level {
+			t.Fatalf("expected %s permission, got %q and state %#v", level, permission, state)
+		}
+	}
+
+	// Changed outside of terraform
+	permission = "read"
+
+	state, err := r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err := r.Diff(state, testResourceConfig(t, raw("admin")), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff.Empty() || diff.RequiresNew() {
+		t.Fatalf("expected the permission to be updated in place, got %#v", diff)
+	}
+
+	if _, err := r.Apply(state, &terraform.InstanceDiff{Destroy: true}, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if permission != "" {
+		t.Fatalf("expected the permission to be removed, got %q", permission)
+	}
+}
+
+func TestRepositoryGroupPermission_import(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo/permissions-config/groups/developers": `{"permission": "write", "group": {"slug": "developers"}}`,
+	}))
+	defer closeServer()
+
+	d := resourceRepositoryGroupPermission().Data(&terraform.InstanceState{ID: "test-owner/test-repo/developers"})
+	if err := resourceRepositoryGroupPermissionRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for key, expected := range map[string]string{
+		"workspace":  "test-owner",
+		"repo_slug":  "test-repo",
+		"group_slug": "developers",
+		"permission": "write",
+	} {
+		if v := d.Get(key).(string); v != expected {
+			t.Fatalf("expected %s to be %q, got %q", key, expected, v)
+		}
+	}
+}
+
+func TestRepositoryGroupPermission_removedOutsideOfTerraform(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(nil))
+	defer closeServer()
+
+	d := resourceRepositoryGroupPermission().Data(&terraform.InstanceState{ID: "test-owner/test-repo/developers"})
+	if err := resourceRepositoryGroupPermissionRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.Id() != "" {
+		t.Fatalf("expected the permission to be removed from state, got %s", d.Id())
+	}
+}
+
+func TestRepositoryGroupPermission_validatesPermission(t *testing.T) {
+	for permission, valid := range map[string]bool{"read": true, "write": true, "admin": true, "owner": false, "Write": false} {
+		_, errs := resourceRepositoryGroupPermission().Validate(testResourceConfig(t, map[string]interface{}{
+			"workspace":  "test-owner",
+			"repo_slug":  "test-repo",
+			"group_slug": "developers",
+			"permission": permission,
+		}))
+
+		if valid != (len(errs) == 0) {
+			t.Fatalf("expected permission %q to be valid %t, got %v", permission, valid, errs)
+		}
+	}
+}
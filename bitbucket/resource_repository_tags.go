@@ -0,0 +1,226 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// Target is the commit a ref points at
+type Target struct {
+	Hash string `json:"hash,omitempty"`
+}
+
+// Tag is a git tag in a repository
+type Tag struct {
+	Name   string `json:"name,omitempty"`
+	Target Target `json:"target,omitempty"`
+}
+
+// PaginatedTags is a paginated list of tags that the bitbucket api returns
+type PaginatedTags struct {
+	Values []Tag  `json:"values,omitempty"`
+	Page   int    `json:"page,omitempty"`
+	Size   int    `json:"size,omitempty"`
+	Next   string `json:"next,omitempty"`
+}
+
+func resourceRepositoryTags() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceRepositoryTagsCreate,
+		Read:   resourceRepositoryTagsRead,
+		Update: resourceRepositoryTagsUpdate,
+		Delete: resourceRepositoryTagsDelete,
+
+		Schema: map[string]*schema.Schema{
+			"owner": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"repository": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"tags": {
+				Type:     schema.TypeSet,
+				Required: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"target": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func expandTags(v interface{}) []Tag {
+	tags := make([]Tag, 0, len(v.(*schema.Set).List()))
+
+	for _, item := range v.(*schema.Set).List() {
+		m := item.(map[string]interface{})
+		tags = append(tags, Tag{Name: m["name"].(string), Target: Target{Hash: m["target"].(string)}})
+	}
+
+	return tags
+}
+
+// diffTags works out which tags need to be created and removed to get from old to new, tags
+// can't be moved so a tag whose target changed is removed and created again
+func diffTags(old, new []Tag) (create, remove []Tag) {
+	oldTags := make(map[string]Tag, len(old))
+	for _, tag := range old {
+		oldTags[tag.Name] = tag
+	}
+
+	newTags := make(map[string]Tag, len(new))
+	for _, tag := range new {
+		newTags[tag.Name] = tag
+	}
+
+	for _, tag := range old {
+		if newTag, ok := newTags[tag.Name]; !ok || newTag.Target != tag.Target {
+			remove = append(remove, tag)
+		}
+	}
+
+	for _, tag := range new {
+		if oldTag, ok := oldTags[tag.Name]; !ok || oldTag.Target != tag.Target {
+			create = append(create, tag)
+		}
+	}
+
+	return create, remove
+}
+
+func createTags(d *schema.ResourceData, client *Client, tags []Tag) error {
+	for _, tag := range tags {
+		bytedata, err := json.Marshal(tag)
+		if err != nil {
+			return err
+		}
+
+		_, err = client.Post(fmt.Sprintf("repositories/%s/%s/refs/tags",
+			d.Get("owner").(string),
+			d.Get("repository").(string),
+		), bytes.NewBuffer(bytedata))
+
+		if err != nil {
+			return fmt.Errorf("Failed to create tag %s: %s", tag.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func removeTags(d *schema.ResourceData, client *Client, tags []Tag) error {
+	for _, tag := range tags {
+		// Somebody else already removed it, or the repository is gone
+		err := client.DeleteIgnoringNotFound(fmt.Sprintf("repositories/%s/%s/refs/tags/%s",
+			d.Get("owner").(string),
+			d.Get("repository").(string),
+			url.PathEscape(tag.Name),
+		))
+
+		if err != nil {
+			return fmt.Errorf("Failed to remove tag %s: %s", tag.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func resourceRepositoryTagsCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	if err := createTags(d, client, expandTags(d.Get("tags"))); err != nil {
+		return err
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", d.Get("owner").(string), d.Get("repository").(string)))
+
+	return resourceRepositoryTagsRead(d, m)
+}
+
+func resourceRepositoryTagsRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	values, err := client.GetPaged(fmt.Sprintf("repositories/%s/%s/refs/tags",
+		d.Get("owner").(string),
+		d.Get("repository").(string),
+	))
+	if err != nil {
+		return err
+	}
+
+	remoteTags := make(map[string]Tag)
+	for _, value := range values {
+		var tag Tag
+		if err := json.Unmarshal(value, &tag); err != nil {
+			return err
+		}
+		remoteTags[tag.Name] = tag
+	}
+
+	// Only the tags we manage are tracked, any other tag in the repository is left alone
+	var terraformTags []map[string]interface{}
+
+	for _, tag := range expandTags(d.Get("tags")) {
+		remoteTag, ok := remoteTags[tag.Name]
+		if !ok {
+			continue
+		}
+
+		// Keep an abbreviated hash from the config as long as it still matches
+		target := remoteTag.Target.Hash
+		if tag.Target.Hash != "" && strings.HasPrefix(target, tag.Target.Hash) {
+			target = tag.Target.Hash
+		}
+
+		terraformTags = append(terraformTags, map[string]interface{}{
+			"name":   tag.Name,
+			"target": target,
+		})
+	}
+
+	d.Set("tags", terraformTags)
+
+	return nil
+}
+
+func resourceRepositoryTagsUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	if d.HasChange("tags") {
+		old, new := d.GetChange("tags")
+		create, remove := diffTags(expandTags(old), expandTags(new))
+
+		if err := removeTags(d, client, remove); err != nil {
+			return err
+		}
+
+		if err := createTags(d, client, create); err != nil {
+			return err
+		}
+	}
+
+	return resourceRepositoryTagsRead(d, m)
+}
+
+func resourceRepositoryTagsDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+	return removeTags(d, client, expandTags(d.Get("tags")))
+}
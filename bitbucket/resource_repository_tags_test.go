@@ -0,0 +1,70 @@
+package bitbucket
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func TestDiffTags(t *testing.T) {
+	old := []Tag{
+		{Name: "v1.0.0", Target: Target{Hash: "aaaaaaa"}},
+		{Name: "v1.1.0", Target: Target{Hash: "bbbbbbb"}},
+		{Name: "latest", Target: Target{Hash: "bbbbbbb"}},
+	}
+	new := []Tag{
+		{Name: "v1.0.0", Target: Target{Hash: "aaaaaaa"}},
+		{Name: "v1.2.0", Target: Target{Hash: "ccccccc"}},
+		{Name: "latest", Target: Target{Hash: "ccccccc"}},
+	}
+
+	create, remove := diffTags(old, new)
+
+	expectedCreate := []Tag{
+		{Name: "v1.2.0", Target: Target{Hash: "ccccccc"}},
+		{Name: "latest", Target: Target{Hash: "ccccccc"}},
+	}
+	expectedRemove := []Tag{
+		{Name: "v1.1.0", Target: Target{Hash: "bbbbbbb"}},
+		{Name: "latest", Target: Target{Hash: "bbbbbbb"}},
+	}
+
+	if !reflect.DeepEqual(create, expectedCreate) {
+		t.Fatalf("expected to create %v, got %v", expectedCreate, create)
+	}
+	if !reflect.DeepEqual(remove, expectedRemove) {
+		t.Fatalf("expected to remove %v, got %v", expectedRemove, remove)
+	}
+}
+
+func TestRepositoryTagsRead(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo/refs/tags": `{"values": [
+			{"name": "v1.0.0", "target": {"hash": "aaaaaaa0000000000000000000000000000000000"}},
+			{"name": "unmanaged", "target": {"hash": "ddddddd0000000000000000000000000000000000"}}
+		]}`,
+	}))
+	defer closeServer()
+
+	d := schema.TestResourceDataRaw(t, resourceRepositoryTags().Schema, map[string]interface{}{
+		"owner":      "test-owner",
+		"repository": "test-repo",
+		"tags": []interface{}{
+			map[string]interface{}{"name": "v1.0.0", "target": "aaaaaaa"},
+			map[string]interface{}{"name": "deleted-outside-terraform", "target": "bbbbbbb"},
+		},
+	})
+	d.SetId("test-owner/test-repo")
+
+	if err := resourceRepositoryTagsRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	tags := expandTags(d.Get("tags"))
+	expected := []Tag{{Name: "v1.0.0", Target: Target{Hash: "aaaaaaa"}}}
+
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("expected tags %v, got %v", expected, tags)
+	}
+}
@@ -1,11 +1,15 @@
 package bitbucket
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -90,3 +94,1887 @@ func testAccCheckBitbucketRepositoryExists(n string, repository *Repository) res
 		return nil
 	}
 }
+
+// testRepositoryResponses fills in empty responses for the endpoints read looks at besides the repository itself
+func testRepositoryResponses(responses map[string]string) map[string]string {
+	defaults := map[string]string{
+		"/2.0/repositories/test-owner/test-repo/pipelines_config":    `{"enabled": false}`,
+		"/2.0/repositories/test-owner/test-repo/branch-restrictions": `{"values": []}`,
+	}
+
+	for path, body := range defaults {
+		if _, ok := responses[path]; !ok {
+			responses[path] = body
+		}
+	}
+
+	return responses
+}
+
+func testRepositoryRead(t *testing.T, responses map[string]string) *schema.ResourceData {
+	client, closeServer := testClient(t, testResponses(testRepositoryResponses(responses)))
+	defer closeServer()
+
+	d := schema.TestResourceDataRaw(t, resourceRepository().Schema, map[string]interface{}{
+		"owner": "test-owner",
+		"name":  "test-repo",
+	})
+	d.SetId("test-owner/test-repo")
+
+	if err := resourceRepositoryRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return d
+}
+
+func TestRepositoryRead_projectName(t *testing.T) {
+	cases := map[string]struct {
+		Response    string
+		ProjectKey  string
+		ProjectName string
+	}{
+		"in a project": {
+			Response:    `{"name": "test-repo", "slug": "test-repo", "project": {"key": "PROJ", "name": "My Project"}}`,
+			ProjectKey:  "PROJ",
+			ProjectName: "My Project",
+		},
+		"without a project": {
+			Response: `{"name": "test-repo", "slug": "test-repo"}`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := testRepositoryRead(t, map[string]string{
+				"/2.0/repositories/test-owner/test-repo": tc.Response,
+			})
+
+			if v := d.Get("project_key").(string); v != tc.ProjectKey {
+				t.Fatalf("expected project_key %q, got %q", tc.ProjectKey, v)
+			}
+			if v := d.Get("project_name").(string); v != tc.ProjectName {
+				t.Fatalf("expected project_name %q, got %q", tc.ProjectName, v)
+			}
+		})
+	}
+}
+
+func TestRepositoryRead_importDisabledWikiAndIssues(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(testRepositoryResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo", "scm": "git", "is_private": true}`,
+	})))
+	defer closeServer()
+
+	// An import starts from nothing but the ID, seed the flags as true so a
+	// read that skips them when the API omits them is caught.
+	d := resourceRepository().Data(nil)
+	d.SetId("test-owner/test-repo")
+	d.Set("has_wiki", true)
+	d.Set("has_issues", true)
+
+	if err := resourceRepositoryRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if d.Get("has_wiki").(bool) {
+		t.Fatal("expected has_wiki to be read as false")
+	}
+	if d.Get("has_issues").(bool) {
+		t.Fatal("expected has_issues to be read as false")
+	}
+}
+
+func TestValidateHTTPURL(t *testing.T) {
+	cases := map[string]bool{
+		"":                         true,
+		"https://example.com":      true,
+		"http://example.com/about": true,
+		"example.com":              false,
+		"ftp://example.com":        false,
+		"https://":                 false,
+	}
+
+	for value, valid := range cases {
+		_, errors := validateHTTPURL(value, "website")
+		if valid && len(errors) > 0 {
+			t.Errorf("expected %q to be valid, got %v", value, errors)
+		}
+		if !valid && len(errors) == 0 {
+			t.Errorf("expected %q to be invalid", value)
+		}
+	}
+}
+
+func TestValidateRepositoryLengths(t *testing.T) {
+	website := func(length int) string {
+		return "https://example.com/" + strings.Repeat("a", length-len("https://example.com/"))
+	}
+
+	cases := []struct {
+		Field string
+		Value string
+		Valid bool
+	}{
+		{"description", "", true},
+		{"description", strings.Repeat("d", maxRepositoryDescriptionLength), true},
+		{"description", strings.Repeat("é", maxRepositoryDescriptionLength), true},
+		{"description", strings.Repeat("d", maxRepositoryDescriptionLength+1), false},
+		{"website", "", true},
+		{"website", website(maxRepositoryWebsiteLength), true},
+		{"website", website(maxRepositoryWebsiteLength + 1), false},
+	}
+
+	for _, tc := range cases {
+		_, errors := resourceRepository().Schema[tc.Field].ValidateFunc(tc.Value, tc.Field)
+		if tc.Valid && len(errors) > 0 {
+			t.Errorf("expected %s of length %d to be valid, got %v", tc.Field, len(tc.Value), errors)
+		}
+		if !tc.Valid && len(errors) == 0 {
+			t.Errorf("expected %s of length %d to be invalid", tc.Field, len(tc.Value))
+		}
+	}
+}
+
+func TestSuppressLanguageDiff(t *testing.T) {
+	cases := []struct {
+		Old, New string
+		Suppress bool
+	}{
+		{Old: "go", New: "Go", Suppress: true},
+		{Old: "python", New: "python", Suppress: true},
+		{Old: "go", New: "python", Suppress: false},
+		{Old: "go", New: "", Suppress: false},
+	}
+
+	for _, tc := range cases {
+		if suppressLanguageDiff("language", tc.Old, tc.New, nil) != tc.Suppress {
+			t.Errorf("expected suppress to be %t for %q -> %q", tc.Suppress, tc.Old, tc.New)
+		}
+	}
+}
+
+func TestRepositoryRead_sizeAndUpdatedOn(t *testing.T) {
+	d := testRepositoryRead(t, map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo", "size": 5368709120, "updated_on": "2020-01-23T10:21:35.123456+01:00"}`,
+	})
+
+	if v := d.Get("size").(int); v != 5368709120 {
+		t.Fatalf("expected size 5368709120, got %d", v)
+	}
+	if v := d.Get("updated_on").(string); v != "2020-01-23T09:21:35Z" {
+		t.Fatalf("expected updated_on 2020-01-23T09:21:35Z, got %s", v)
+	}
+}
+
+func TestRepositoryRead_createdOn(t *testing.T) {
+	d := testRepositoryRead(t, map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo", "created_on": "2015-06-02T18:04:11.845373+00:00", "updated_on": "2020-01-23T09:21:35+00:00"}`,
+	})
+
+	if v := d.Get("created_on").(string); v != "2015-06-02T18:04:11Z" {
+		t.Fatalf("expected created_on 2015-06-02T18:04:11Z, got %s", v)
+	}
+
+	d = testRepositoryRead(t, map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo"}`,
+	})
+
+	if v := d.Get("created_on").(string); v != "" {
+		t.Fatalf("expected created_on to be empty, got %s", v)
+	}
+}
+
+func TestRepositoryRead_avatarURL(t *testing.T) {
+	d := testRepositoryRead(t, map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo", "links": {"avatar": {"href": "https://bytebucket.org/ravatar/%7B1234%7D?ts=default"}}}`,
+	})
+
+	if v := d.Get("avatar_url").(string); v != "https://bytebucket.org/ravatar/%7B1234%7D?ts=default" {
+		t.Fatalf("expected the avatar url from the links, got %s", v)
+	}
+
+	d = testRepositoryRead(t, map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo"}`,
+	})
+
+	if v := d.Get("avatar_url").(string); v != "" {
+		t.Fatalf("expected avatar_url to be empty, got %s", v)
+	}
+}
+
+func TestRepositoryRead_selectsFields(t *testing.T) {
+	var fields string
+
+	responses := testRepositoryResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo", "mainbranch": {"name": "main"},
+			"project": {"key": "PROJ", "name": "My Project"},
+			"links": {"clone": [{"name": "https", "href": "https://bitbucket.org/test-owner/test-repo.git"}]}}`,
+	})
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/2.0/repositories/test-owner/test-repo" {
+			fields = r.URL.Query().Get("fields")
+		}
+		testResponses(responses)(w, r)
+	}))
+	defer closeServer()
+
+	d := schema.TestResourceDataRaw(t, resourceRepository().Schema, map[string]interface{}{
+		"owner": "test-owner",
+		"name":  "test-repo",
+	})
+	d.SetId("test-owner/test-repo")
+
+	if err := resourceRepositoryRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, field := range []string{"mainbranch.name", "project.key", "project.name", "links.clone"} {
+		if !strings.Contains(","+fields+",", ","+field+",") {
+			t.Fatalf("expected %s to be asked for, got fields=%q", field, fields)
+		}
+	}
+
+	for key, expected := range map[string]string{
+		"main_branch":  "main",
+		"project_key":  "PROJ",
+		"project_name": "My Project",
+		"clone_https":  "https://bitbucket.org/test-owner/test-repo.git",
+	} {
+		if v := d.Get(key).(string); v != expected {
+			t.Fatalf("expected %s to be %q, got %q", key, expected, v)
+		}
+	}
+}
+
+func TestRepositoryRead_emptyRepository(t *testing.T) {
+	d := testRepositoryRead(t, map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo", "mainbranch": null}`,
+	})
+
+	if v := d.Get("main_branch").(string); v != "" {
+		t.Fatalf("expected main_branch to be empty, got %s", v)
+	}
+}
+
+func TestGetRepositoryMainBranch_emptyRepository(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo", "mainbranch": null}`,
+	}))
+	defer closeServer()
+
+	_, err := getRepositoryMainBranch(client, "test-owner", "test-repo")
+	if err == nil || !strings.Contains(err.Error(), "has no branches yet") {
+		t.Fatalf("expected a no branches yet error, got %v", err)
+	}
+}
+
+func TestValidateForkPolicy(t *testing.T) {
+	cases := map[string]struct {
+		IsPrivate  bool
+		ForkPolicy string
+		Valid      bool
+	}{
+		"private allowing forks":          {IsPrivate: true, ForkPolicy: "allow_forks", Valid: true},
+		"private with only private forks": {IsPrivate: true, ForkPolicy: "no_public_forks", Valid: true},
+		"private without forks":           {IsPrivate: true, ForkPolicy: "no_forks", Valid: true},
+		"public allowing forks":           {IsPrivate: false, ForkPolicy: "allow_forks", Valid: true},
+		"public without forks":            {IsPrivate: false, ForkPolicy: "no_forks", Valid: true},
+		"public with only private forks":  {IsPrivate: false, ForkPolicy: "no_public_forks"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := validateForkPolicy(tc.IsPrivate, tc.ForkPolicy)
+			if (err == nil) != tc.Valid {
+				t.Fatalf("expected valid %t, got %v", tc.Valid, err)
+			}
+		})
+	}
+
+	// Caught when planning
+	_, err := resourceRepository().Diff(nil, testResourceConfig(t, map[string]interface{}{
+		"owner":       "test-owner",
+		"name":        "test-repo",
+		"is_private":  false,
+		"fork_policy": "no_public_forks",
+	}), nil)
+	if err == nil || !strings.Contains(err.Error(), "only valid for a private repository") {
+		t.Fatalf("expected the plan to fail, got %v", err)
+	}
+}
+
+func TestRepository_forkPolicyValues(t *testing.T) {
+	for forkPolicy, valid := range map[string]bool{
+		"allow_forks":     true,
+		"no_public_forks": true,
+		"no_forks":        true,
+		"allow-forks":     false,
+		"none":            false,
+	} {
+		_, errors := resourceRepository().Validate(testResourceConfig(t, map[string]interface{}{
+			"owner":       "test-owner",
+			"name":        "test-repo",
+			"fork_policy": forkPolicy,
+		}))
+
+		if valid && len(errors) > 0 {
+			t.Errorf("expected fork_policy %s to be valid, got %v", forkPolicy, errors)
+		}
+		if !valid && (len(errors) == 0 || !strings.Contains(errors[0].Error(), "fork_policy")) {
+			t.Errorf("expected fork_policy %s to be rejected when planning, got %v", forkPolicy, errors)
+		}
+	}
+}
+
+func TestRepositoryDependentsWarning(t *testing.T) {
+	cases := map[string]struct {
+		Responses map[string]string
+		Warning   string
+	}{
+		"with dependents": {
+			Responses: map[string]string{
+				"/2.0/repositories/test-owner/test-repo/pullrequests": `{"pagelen": 1, "size": 3, "values": [{"id": 1}]}`,
+				"/2.0/repositories/test-owner/test-repo/deploy-keys":  `{"pagelen": 1, "size": 0, "values": []}`,
+				"/2.0/repositories/test-owner/test-repo/hooks":        `{"pagelen": 1, "size": 2, "values": [{"uuid": "{hook}"}]}`,
+			},
+			Warning: "Deleting repository test-owner/test-repo also deletes its 3 open pull requests, 2 webhooks",
+		},
+		"without dependents": {
+			Responses: map[string]string{
+				"/2.0/repositories/test-owner/test-repo/pullrequests": `{"pagelen": 1, "size": 0, "values": []}`,
+			},
+		},
+		"counts that can't be read": {
+			Responses: map[string]string{
+				"/2.0/repositories/test-owner/test-repo/pullrequests": `not json`,
+				"/2.0/repositories/test-owner/test-repo/hooks":        `{"pagelen": 1, "size": 1, "values": [{"uuid": "{hook}"}]}`,
+			},
+			Warning: "Deleting repository test-owner/test-repo also deletes its 1 webhooks",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("pagelen") != "1" {
+					t.Errorf("expected just one item to be asked for, got %s", r.URL)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if strings.HasSuffix(r.URL.Path, "/pullrequests") && r.URL.Query().Get("state") != "OPEN" {
+					t.Errorf("expected only open pull requests to be counted, got %s", r.URL)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				testResponses(tc.Responses)(w, r)
+			}))
+			defer closeServer()
+
+			if warning := repositoryDependentsWarning(client, "test-owner", "test-repo"); warning != tc.Warning {
+				t.Fatalf("expected warning %q, got %q", tc.Warning, warning)
+			}
+		})
+	}
+}
+
+func TestRepository_warnOnDestroyWhenReplacing(t *testing.T) {
+	cases := map[string]struct {
+		SCM     string
+		Counted bool
+	}{
+		"replaced": {
+			SCM:     "hg",
+			Counted: true,
+		},
+		"updated in place": {
+			SCM: "git",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var counted []string
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				counted = append(counted, r.URL.Path)
+				w.Write([]byte(`{"pagelen": 1, "size": 1, "values": [{}]}`))
+			}))
+			defer closeServer()
+
+			diff, err := resourceRepository().Diff(&terraform.InstanceState{
+				ID: "test-owner/test-repo",
+				Attributes: map[string]string{
+					"owner":           "test-owner",
+					"name":            "test-repo",
+					"scm":             "git",
+					"warn_on_destroy": "true",
+				},
+			}, testResourceConfig(t, map[string]interface{}{
+				"owner":           "test-owner",
+				"name":            "test-repo",
+				"scm":             tc.SCM,
+				"warn_on_destroy": true,
+			}), client)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if diff.RequiresNew() != tc.Counted || (len(counted) > 0) != tc.Counted {
+				t.Fatalf("expected the dependents to be counted only when replacing, got %v for %#v", counted, diff)
+			}
+		})
+	}
+}
+
+func TestRepositoryRead_dependentCounts(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(testRepositoryResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo":              `{"name": "test-repo", "slug": "test-repo"}`,
+		"/2.0/repositories/test-owner/test-repo/pullrequests": `{"pagelen": 1, "size": 3, "values": [{}]}`,
+		"/2.0/repositories/test-owner/test-repo/deploy-keys":  `{"pagelen": 1, "size": 1, "values": [{}]}`,
+		// The webhooks can't be counted
+		"/2.0/repositories/test-owner/test-repo/hooks": `not json`,
+	})))
+	defer closeServer()
+
+	for _, warnOnDestroy := range []bool{true, false} {
+		d := schema.TestResourceDataRaw(t, resourceRepository().Schema, map[string]interface{}{
+			"owner":           "test-owner",
+			"name":            "test-repo",
+			"warn_on_destroy": warnOnDestroy,
+		})
+		d.SetId("test-owner/test-repo")
+
+		if err := resourceRepositoryRead(d, client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		expected := map[string]int{"open_pull_requests": 3, "deploy_key_count": 1, "webhook_count": 0}
+		if !warnOnDestroy {
+			expected = map[string]int{"open_pull_requests": 0, "deploy_key_count": 0, "webhook_count": 0}
+		}
+		for key, count := range expected {
+			if v := d.Get(key).(int); v != count {
+				t.Fatalf("expected %s to be %d with warn_on_destroy %t, got %d", key, count, warnOnDestroy, v)
+			}
+		}
+	}
+}
+
+func TestRepositoryDelete_warnOnDestroyDoesNotBlock(t *testing.T) {
+	var deleted bool
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "DELETE" && r.URL.Path == "/2.0/repositories/test-owner/test-repo":
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			// None of the dependents can be counted
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer closeServer()
+
+	d := schema.TestResourceDataRaw(t, resourceRepository().Schema, map[string]interface{}{
+		"owner":           "test-owner",
+		"name":            "test-repo",
+		"warn_on_destroy": true,
+	})
+	d.SetId("test-owner/test-repo")
+
+	if err := resourceRepositoryDelete(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !deleted {
+		t.Fatal("expected the repository to be deleted")
+	}
+}
+
+func TestRepositorySizeWarning(t *testing.T) {
+	cases := map[string]struct {
+		Size      int
+		Threshold int
+		Warns     bool
+	}{
+		"above the threshold": {
+			Size:      2048,
+			Threshold: 1024,
+			Warns:     true,
+		},
+		"below the threshold": {
+			Size:      512,
+			Threshold: 1024,
+		},
+		"no threshold": {
+			Size: 2048,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			warning := repositorySizeWarning("test-owner/test-repo", tc.Size, tc.Threshold)
+			if (warning != "") != tc.Warns {
+				t.Fatalf("expected warning %t, got %q", tc.Warns, warning)
+			}
+		})
+	}
+
+	// Only ever a warning, the plan goes ahead
+	r := resourceRepository()
+	state := &terraform.InstanceState{
+		ID: "test-owner/test-repo",
+		Attributes: map[string]string{
+			"owner":               "test-owner",
+			"name":                "test-repo",
+			"scm":                 "git",
+			"fork_policy":         "allow_forks",
+			"is_private":          "true",
+			"size":                "2048",
+			"size_warn_threshold": "1024",
+		},
+	}
+
+	_, err := r.Diff(state, testResourceConfig(t, map[string]interface{}{
+		"owner":               "test-owner",
+		"name":                "test-repo",
+		"size_warn_threshold": 1024,
+	}), nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Lowering the threshold shows in the plan
+	diff, err := r.Diff(state, testResourceConfig(t, map[string]interface{}{
+		"owner":               "test-owner",
+		"name":                "test-repo",
+		"size_warn_threshold": 4096,
+	}), nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if attr := diff.Attributes["size_over_threshold"]; attr == nil || attr.New != "false" {
+		t.Fatalf("expected size_over_threshold to be planned as false, got %#v", attr)
+	}
+}
+
+func TestRepositoryRead_sizeOverThreshold(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(testRepositoryResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo", "size": 2048}`,
+	})))
+	defer closeServer()
+
+	for threshold, expected := range map[int]bool{0: false, 1024: true, 4096: false} {
+		d := schema.TestResourceDataRaw(t, resourceRepository().Schema, map[string]interface{}{
+			"owner":               "test-owner",
+			"name":                "test-repo",
+			"size_warn_threshold": threshold,
+		})
+		d.SetId("test-owner/test-repo")
+
+		if err := resourceRepositoryRead(d, client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if v := d.Get("size_over_threshold").(bool); v != expected {
+			t.Fatalf("expected size_over_threshold %t with a threshold of %d, got %t", expected, threshold, v)
+		}
+	}
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	cases := map[string]string{
+		"":                                 "",
+		"2020-01-23T10:21:35+00:00":        "2020-01-23T10:21:35Z",
+		"2020-01-23T10:21:35.123456+00:00": "2020-01-23T10:21:35Z",
+		"not a timestamp":                  "not a timestamp",
+	}
+
+	for in, expected := range cases {
+		if out := formatTimestamp(in); out != expected {
+			t.Errorf("expected %q to format as %q, got %q", in, expected, out)
+		}
+	}
+}
+
+func TestSetRepositoryArchived(t *testing.T) {
+	cases := map[string]struct {
+		Archived     bool
+		Restrictions string
+		Method       string
+		Path         string
+	}{
+		"archive": {
+			Archived:     true,
+			Restrictions: `{"values": []}`,
+			Method:       "POST",
+			Path:         "/2.0/repositories/test-owner/test-repo/branch-restrictions",
+		},
+		"already archived": {
+			Archived:     true,
+			Restrictions: `{"values": [{"id": 3, "kind": "push", "pattern": "*"}]}`,
+		},
+		"unarchive": {
+			Archived:     false,
+			Restrictions: `{"values": [{"id": 3, "kind": "push", "pattern": "*"}]}`,
+			Method:       "DELETE",
+			Path:         "/2.0/repositories/test-owner/test-repo/branch-restrictions/3",
+		},
+		"push restriction with users isn't an archive": {
+			Archived:     false,
+			Restrictions: `{"values": [{"id": 4, "kind": "push", "pattern": "*", "users": [{"username": "gob"}]}]}`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var method, path string
+
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "GET" {
+					if q := r.URL.Query(); q.Get("kind") != "push" || q.Get("pattern") != "*" {
+						t.Errorf("unexpected query %s", r.URL.RawQuery)
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					w.Write([]byte(tc.Restrictions))
+					return
+				}
+				method, path = r.Method, r.URL.Path
+				w.Write([]byte(`{}`))
+			}))
+			defer closeServer()
+
+			if err := setRepositoryArchived(client, "test-owner", "test-repo", tc.Archived); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if method != tc.Method || path != tc.Path {
+				t.Fatalf("expected %q %q, got %q %q", tc.Method, tc.Path, method, path)
+			}
+		})
+	}
+}
+
+func TestRepositoryRead_mismatchedCloneURL(t *testing.T) {
+	cases := map[string]struct {
+		CloneURL string
+		Matches  bool
+	}{
+		"same repository": {
+			CloneURL: "https://gob@bitbucket.org/test-owner/test-repo.git",
+			Matches:  true,
+		},
+		"different casing": {
+			CloneURL: "https://bitbucket.org/Test-Owner/Test-Repo.git",
+			Matches:  true,
+		},
+		"another repository": {
+			CloneURL: "https://gob@bitbucket.org/test-owner/other-repo.git",
+		},
+		"another owner": {
+			CloneURL: "https://gob@bitbucket.org/other-owner/test-repo.git",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := testRepositoryRead(t, map[string]string{
+				"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo",
+					"links": {"clone": [{"name": "https", "href": "` + tc.CloneURL + `"}]}}`,
+			})
+
+			if tc.Matches && d.Id() != "test-owner/test-repo" {
+				t.Fatalf("expected the repository to be kept, got ID %q", d.Id())
+			}
+			if !tc.Matches && d.Id() != "" {
+				t.Fatalf("expected the repository to be removed from state, got ID %q", d.Id())
+			}
+		})
+	}
+}
+
+func TestRepositoryRead_wikiCloneURLs(t *testing.T) {
+	links := `"links": {"clone": [
+		{"name": "https", "href": "https://gob@bitbucket.org/test-owner/test-repo.git"},
+		{"name": "ssh", "href": "git@bitbucket.org:test-owner/test-repo.git"}
+	]}`
+
+	cases := map[string]struct {
+		HasWiki bool
+		HTTPS   string
+		SSH     string
+	}{
+		"wiki enabled": {
+			HasWiki: true,
+			HTTPS:   "https://gob@bitbucket.org/test-owner/test-repo.git/wiki",
+			SSH:     "git@bitbucket.org:test-owner/test-repo.git/wiki",
+		},
+		"wiki disabled": {
+			HasWiki: false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := testRepositoryRead(t, map[string]string{
+				"/2.0/repositories/test-owner/test-repo": fmt.Sprintf(`{"name": "test-repo", "slug": "test-repo", "has_wiki": %t, %s}`, tc.HasWiki, links),
+			})
+
+			if v := d.Get("wiki_clone_https").(string); v != tc.HTTPS {
+				t.Fatalf("expected wiki_clone_https %q, got %q", tc.HTTPS, v)
+			}
+			if v := d.Get("wiki_clone_ssh").(string); v != tc.SSH {
+				t.Fatalf("expected wiki_clone_ssh %q, got %q", tc.SSH, v)
+			}
+		})
+	}
+}
+
+func TestRepositoryCreate_pipelinesOnlyConfiguredWhenSet(t *testing.T) {
+	cases := map[string]struct {
+		Config   map[string]interface{}
+		Expected bool
+	}{
+		"unset": {
+			Config: map[string]interface{}{"owner": "test-owner", "name": "test-repo"},
+		},
+		"explicitly disabled": {
+			Config:   map[string]interface{}{"owner": "test-owner", "name": "test-repo", "pipelines_enabled": false},
+			Expected: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var configured bool
+
+			responses := testRepositoryResponses(map[string]string{
+				"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo"}`,
+				// Inherited from the workspace
+				"/2.0/repositories/test-owner/test-repo/pipelines_config": `{"enabled": true}`,
+			})
+
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "PUT" && r.URL.Path == "/2.0/repositories/test-owner/test-repo/pipelines_config" {
+					configured = true
+				}
+				testResponses(responses)(w, r)
+			}))
+			defer closeServer()
+
+			r := resourceRepository()
+			diff, err := r.Diff(nil, testResourceConfig(t, tc.Config), client)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			state, err := r.Apply(nil, diff, client)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if configured != tc.Expected {
+				t.Fatalf("expected pipelines to be configured %t, got %t", tc.Expected, configured)
+			}
+			if !tc.Expected && state.Attributes["pipelines_enabled"] != "true" {
+				t.Fatalf("expected the inherited setting to be read, got %q", state.Attributes["pipelines_enabled"])
+			}
+		})
+	}
+}
+
+func TestRepositoryCreate_slugCaseConflict(t *testing.T) {
+	cases := map[string]struct {
+		Existing string
+		Error    string
+	}{
+		"differs in case": {
+			Existing: "myrepo",
+			Error:    "Repository test-owner/MyRepo can't be created, slugs are unique regardless of case and the repository test-owner/myrepo already exists",
+		},
+		"same slug": {
+			Existing: "MyRepo",
+			Error:    "API Error: 400 POST 2.0/repositories/test-owner/MyRepo Repository with this Slug and Owner already exists.",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "POST" {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"type": "error", "error": {"message": "Repository with this Slug and Owner already exists."}}`))
+					return
+				}
+				fmt.Fprintf(w, `{"name": %q, "slug": %q}`, tc.Existing, tc.Existing)
+			}))
+			defer closeServer()
+
+			r := resourceRepository()
+			diff, err := r.Diff(nil, testResourceConfig(t, map[string]interface{}{
+				"owner": "test-owner",
+				"name":  "MyRepo",
+			}), client)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			_, err = r.Apply(nil, diff, client)
+			if err == nil || !strings.Contains(err.Error(), tc.Error) {
+				t.Fatalf("expected error containing %q, got %v", tc.Error, err)
+			}
+		})
+	}
+}
+
+func TestRepositoryCreate_configurationFailsAfterCreate(t *testing.T) {
+	responses := testRepositoryResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo"}`,
+	})
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/2.0/repositories/test-owner/test-repo":
+			w.Write([]byte(responses[r.URL.Path]))
+		case r.Method == "PUT" && r.URL.Path == "/2.0/repositories/test-owner/test-repo/pipelines_config":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"type": "error", "error": {"message": "Something went wrong"}}`))
+		case r.Method == "GET":
+			testResponses(responses)(w, r)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer closeServer()
+
+	d := schema.TestResourceDataRaw(t, resourceRepository().Schema, map[string]interface{}{
+		"owner":             "test-owner",
+		"name":              "test-repo",
+		"pipelines_enabled": true,
+	})
+
+	err := resourceRepositoryCreate(d, client)
+	if err == nil || !strings.Contains(err.Error(), "Repository test-owner/test-repo was created but configuring it failed") {
+		t.Fatalf("expected the error to say the repository was created, got %v", err)
+	}
+
+	if d.Id() != "test-owner/test-repo" {
+		t.Fatalf("expected the ID to be kept, got %q", d.Id())
+	}
+
+	// State reflects the server so an update can finish the job
+	if d.Get("pipelines_enabled").(bool) {
+		t.Fatal("expected pipelines_enabled to be read back as false")
+	}
+}
+
+// testRepositoryUpdate plans config against a repository with the given state and applies it
+func testRepositoryUpdate(t *testing.T, client *Client, attributes map[string]string, raw map[string]interface{}) error {
+	r := resourceRepository()
+
+	state := &terraform.InstanceState{
+		ID:         "test-owner/test-repo",
+		Attributes: attributes,
+	}
+
+	diff, err := r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = r.Apply(state, diff, client)
+	return err
+}
+
+func TestRepositoryUpdate_moveToProject(t *testing.T) {
+	cases := map[string]struct {
+		ProjectKey string
+		Error      string
+	}{
+		"existing project": {
+			ProjectKey: "GOOD",
+		},
+		"lowercase key": {
+			ProjectKey: "good",
+		},
+		"project name": {
+			ProjectKey: "good project",
+		},
+		"ambiguous project name": {
+			ProjectKey: "Shared",
+			Error:      "project name Shared is ambiguous in workspace test-owner, it matches the projects ONE, TWO",
+		},
+		"missing project": {
+			ProjectKey: "MISSING",
+			Error:      "project MISSING not found in workspace test-owner",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var moved bool
+
+			responses := testRepositoryResponses(map[string]string{
+				"/2.0/repositories/test-owner/test-repo":   `{"name": "test-repo", "slug": "test-repo", "project": {"key": "GOOD"}}`,
+				"/2.0/workspaces/test-owner/projects/GOOD": `{"key": "GOOD"}`,
+				"/2.0/workspaces/test-owner/projects": `{"values": [{"key": "GOOD", "name": "Good Project"},
+					{"key": "ONE", "name": "Shared"}, {"key": "TWO", "name": "Shared"}]}`,
+			})
+
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "PUT" && r.URL.Path == "/2.0/repositories/test-owner/test-repo" {
+					var payload Repository
+					json.NewDecoder(r.Body).Decode(&payload)
+					if payload.Project.Key != "GOOD" {
+						t.Errorf("expected the repository to be moved to GOOD, got %q", payload.Project.Key)
+					}
+					moved = true
+				}
+				testResponses(responses)(w, r)
+			}))
+			defer closeServer()
+
+			err := testRepositoryUpdate(t, client, map[string]string{
+				"owner":       "test-owner",
+				"name":        "test-repo",
+				"slug":        "test-repo",
+				"scm":         "git",
+				"fork_policy": "allow_forks",
+				"is_private":  "true",
+				"project_key": "OLD",
+			}, map[string]interface{}{
+				"owner":       "test-owner",
+				"name":        "test-repo",
+				"project_key": tc.ProjectKey,
+			})
+
+			if tc.Error != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.Error) {
+					t.Fatalf("expected error containing %q, got %v", tc.Error, err)
+				}
+				if moved {
+					t.Fatal("expected the repository not to be moved")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !moved {
+				t.Fatal("expected the repository to be moved")
+			}
+		})
+	}
+}
+
+func TestRepositoryUpdate_mainBranch(t *testing.T) {
+	cases := map[string]struct {
+		Stored   string
+		Current  string
+		Expected string
+		Error    string
+	}{
+		"repository with commits": {
+			Stored:   `{"name": "test-repo", "slug": "test-repo", "mainbranch": {"name": "master"}}`,
+			Current:  "master",
+			Expected: "develop",
+		},
+		"empty repository": {
+			Stored:  `{"name": "test-repo", "slug": "test-repo", "mainbranch": null}`,
+			Current: "",
+			Error:   "has no branches yet",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var mainBranch string
+
+			responses := testRepositoryResponses(map[string]string{
+				"/2.0/repositories/test-owner/test-repo": tc.Stored,
+			})
+
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "PUT" && r.URL.Path == "/2.0/repositories/test-owner/test-repo" {
+					var payload Repository
+					json.NewDecoder(r.Body).Decode(&payload)
+					if payload.Mainbranch != nil {
+						mainBranch = payload.Mainbranch.Name
+					}
+				}
+				testResponses(responses)(w, r)
+			}))
+			defer closeServer()
+
+			err := testRepositoryUpdate(t, client, map[string]string{
+				"owner":       "test-owner",
+				"name":        "test-repo",
+				"slug":        "test-repo",
+				"scm":         "git",
+				"fork_policy": "allow_forks",
+				"is_private":  "true",
+				"main_branch": tc.Current,
+			}, map[string]interface{}{
+				"owner":       "test-owner",
+				"name":        "test-repo",
+				"main_branch": "develop",
+			})
+
+			if tc.Error != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.Error) {
+					t.Fatalf("expected error containing %q, got %v", tc.Error, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if mainBranch != tc.Expected {
+				t.Fatalf("expected the main branch to be set to %q, got %q", tc.Expected, mainBranch)
+			}
+		})
+	}
+}
+
+func TestRepository_defaultProjectIsNotDrift(t *testing.T) {
+	r := resourceRepository()
+	state := &terraform.InstanceState{
+		ID: "test-owner/test-repo",
+		Attributes: map[string]string{
+			"owner":             "test-owner",
+			"name":              "test-repo",
+			"scm":               "git",
+			"fork_policy":       "allow_forks",
+			"is_private":        "true",
+			"has_wiki":          "false",
+			"has_issues":        "false",
+			"archived":          "false",
+			"pipelines_enabled": "false",
+			"project_key":       "DEFAULT",
+			"issue_tracker.#":   "0",
+		},
+	}
+
+	diff, err := r.Diff(state, testResourceConfig(t, map[string]interface{}{
+		"owner": "test-owner",
+		"name":  "test-repo",
+	}), nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected the assigned project not to show as drift, got %#v", diff.Attributes)
+	}
+
+	diff, err = r.Diff(state, testResourceConfig(t, map[string]interface{}{
+		"owner":       "test-owner",
+		"name":        "test-repo",
+		"project_key": "OTHER",
+	}), nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff.Empty() {
+		t.Fatal("expected moving to another project to show a diff")
+	}
+}
+
+func TestRepository_projectNameIsNotDrift(t *testing.T) {
+	r := resourceRepository()
+	state := &terraform.InstanceState{
+		ID: "test-owner/test-repo",
+		Attributes: map[string]string{
+			"owner":             "test-owner",
+			"name":              "test-repo",
+			"scm":               "git",
+			"fork_policy":       "allow_forks",
+			"is_private":        "true",
+			"has_wiki":          "false",
+			"has_issues":        "false",
+			"archived":          "false",
+			"pipelines_enabled": "false",
+			"project_key":       "GOOD",
+			"project_name":      "Good Project",
+			"issue_tracker.#":   "0",
+		},
+	}
+
+	for project, expectDiff := range map[string]bool{"GOOD": false, "good": false, "Good Project": false, "Other Project": true} {
+		diff, err := r.Diff(state, testResourceConfig(t, map[string]interface{}{
+			"owner":       "test-owner",
+			"name":        "test-repo",
+			"project_key": project,
+		}), nil)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if diff.Empty() == expectDiff {
+			t.Fatalf("expected a diff for project_key %q to be %t, got %#v", project, expectDiff, diff)
+		}
+	}
+}
+
+func TestRepository_projectlessRepositoryIsNotDrift(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(testRepositoryResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo", "scm": "git",
+			"fork_policy": "allow_forks", "is_private": true}`,
+	})))
+	defer closeServer()
+
+	r := resourceRepository()
+	state, err := r.Refresh(&terraform.InstanceState{
+		ID: "test-owner/test-repo",
+		Attributes: map[string]string{
+			"owner": "test-owner",
+			"name":  "test-repo",
+		},
+	}, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if v, ok := state.Attributes["project_key"]; !ok || v != "" {
+		t.Fatalf("expected an empty project_key, got %q", v)
+	}
+
+	diff, err := r.Diff(state, testResourceConfig(t, map[string]interface{}{
+		"owner":       "test-owner",
+		"name":        "test-repo",
+		"project_key": "",
+	}), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected no diff, got %#v", diff.Attributes)
+	}
+}
+
+func TestRepositoryUpdate_clearLanguage(t *testing.T) {
+	var sent map[string]interface{}
+
+	responses := testRepositoryResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo", "is_private": true}`,
+	})
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" && r.URL.Path == "/2.0/repositories/test-owner/test-repo" {
+			json.NewDecoder(r.Body).Decode(&sent)
+		}
+		testResponses(responses)(w, r)
+	}))
+	defer closeServer()
+
+	err := testRepositoryUpdate(t, client, map[string]string{
+		"owner":       "test-owner",
+		"name":        "test-repo",
+		"slug":        "test-repo",
+		"scm":         "git",
+		"fork_policy": "allow_forks",
+		"is_private":  "true",
+		"language":    "go",
+	}, map[string]interface{}{
+		"owner":    "test-owner",
+		"name":     "test-repo",
+		"language": "",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(sent) != 1 {
+		t.Fatalf("expected only language to be sent, got %v", sent)
+	}
+	if language, ok := sent["language"]; !ok || language != "" {
+		t.Fatalf("expected an explicit empty language, got %v", sent)
+	}
+}
+
+func TestRepositoryUpdate_keepsUnmodeledFields(t *testing.T) {
+	repo := map[string]interface{}{
+		"name":        "test-repo",
+		"slug":        "test-repo",
+		"is_private":  true,
+		"description": "old",
+		"mystery":     map[string]interface{}{"enabled": true},
+	}
+
+	responses := testRepositoryResponses(map[string]string{})
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2.0/repositories/test-owner/test-repo" {
+			testResponses(responses)(w, r)
+			return
+		}
+
+		// Like bitbucket, every field sent replaces the one stored, including with null
+		if r.Method == "PUT" {
+			var sent map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&sent)
+			for field, value := range sent {
+				repo[field] = value
+			}
+		}
+		json.NewEncoder(w).Encode(repo)
+	}))
+	defer closeServer()
+
+	err := testRepositoryUpdate(t, client, map[string]string{
+		"owner":       "test-owner",
+		"name":        "test-repo",
+		"slug":        "test-repo",
+		"scm":         "git",
+		"fork_policy": "allow_forks",
+		"is_private":  "true",
+		"description": "old",
+	}, map[string]interface{}{
+		"owner":       "test-owner",
+		"name":        "test-repo",
+		"description": "new",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if repo["description"] != "new" {
+		t.Fatalf("expected the description to be updated, got %v", repo)
+	}
+	if mystery, ok := repo["mystery"].(map[string]interface{}); !ok || mystery["enabled"] != true {
+		t.Fatalf("expected the field terraform doesn't know about to be kept, got %v", repo)
+	}
+}
+
+func TestRepository_forkPolicyRoundTrips(t *testing.T) {
+	for _, forkPolicy := range []string{"allow_forks", "no_public_forks", "no_forks"} {
+		t.Run(forkPolicy, func(t *testing.T) {
+			var repo []byte
+
+			responses := testRepositoryResponses(map[string]string{})
+
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/2.0/repositories/test-owner/test-repo" {
+					testResponses(responses)(w, r)
+					return
+				}
+
+				if r.Method == "POST" {
+					var sent map[string]interface{}
+					json.NewDecoder(r.Body).Decode(&sent)
+					sent["slug"] = "test-repo"
+					repo, _ = json.Marshal(sent)
+				}
+				w.Write(repo)
+			}))
+			defer closeServer()
+
+			raw := map[string]interface{}{
+				"owner":       "test-owner",
+				"name":        "test-repo",
+				"fork_policy": forkPolicy,
+			}
+
+			r := resourceRepository()
+			diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			state, err := r.Apply(nil, diff, client)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			state, err = r.Refresh(state, client)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !diff.Empty() {
+				t.Fatalf("expected no drift, got %#v", diff.Attributes)
+			}
+		})
+	}
+}
+
+func TestRepositoryCreate_workspaceDisablesWikis(t *testing.T) {
+	repo := `{"name": "test-repo", "slug": "test-repo", "scm": "git", "fork_policy": "allow_forks", "is_private": true, "has_wiki": false}`
+	responses := testRepositoryResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo": repo,
+	})
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.Write([]byte(repo))
+			return
+		}
+		testResponses(responses)(w, r)
+	}))
+	defer closeServer()
+
+	raw := map[string]interface{}{
+		"owner":    "test-owner",
+		"name":     "test-repo",
+		"has_wiki": true,
+	}
+
+	r := resourceRepository()
+	diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if state.Attributes["has_wiki"] != "false" || state.Attributes["wiki_disabled_by_workspace"] != "true" {
+		t.Fatalf("expected the enforced has_wiki in state, got %v", state.Attributes)
+	}
+
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected no perpetual diff, got %#v", diff.Attributes)
+	}
+}
+
+func TestRepositoryCreate_workspaceEnforcesPrivacy(t *testing.T) {
+	cases := map[string]struct {
+		Enforced bool
+		Error    string
+	}{
+		"public repository allowed": {
+			Enforced: false,
+		},
+		"workspace forces private repositories": {
+			Enforced: true,
+			Error:    "the workspace test-owner only allows private repositories so set is_private = true",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var sent map[string]interface{}
+			repo := fmt.Sprintf(`{"name": "test-repo", "slug": "test-repo", "is_private": %t}`, tc.Enforced)
+			responses := testRepositoryResponses(map[string]string{
+				"/2.0/repositories/test-owner/test-repo": repo,
+			})
+
+			client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "POST" {
+					json.NewDecoder(r.Body).Decode(&sent)
+					w.Write([]byte(repo))
+					return
+				}
+				testResponses(responses)(w, r)
+			}))
+			defer closeServer()
+
+			d := schema.TestResourceDataRaw(t, resourceRepository().Schema, map[string]interface{}{
+				"owner":      "test-owner",
+				"name":       "test-repo",
+				"is_private": false,
+			})
+
+			err := resourceRepositoryCreate(d, client)
+
+			if sent["is_private"] != false {
+				t.Fatalf("expected is_private=false to be sent, got %v", sent)
+			}
+
+			if tc.Error != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.Error) {
+					t.Fatalf("expected error containing %q, got %v", tc.Error, err)
+				}
+				if !d.Get("is_private").(bool) {
+					t.Fatal("expected state to hold the enforced is_private")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+		})
+	}
+}
+
+func TestRepositoryUpdate_inlineBranchRestrictions(t *testing.T) {
+	restrictionsPath := "/2.0/repositories/test-owner/test-repo/branch-restrictions"
+	restrictions := map[string]string{
+		restrictionsPath + "/5": `{"id": 5, "kind": "push", "pattern": "master", "users": [{"username": "gob"}]}`,
+	}
+	nextID := 6
+	var deleted []string
+
+	responses := testRepositoryResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo": `{"name": "test-repo", "slug": "test-repo", "is_private": true}`,
+	})
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == restrictionsPath:
+			var restriction map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&restriction)
+			restriction["id"] = nextID
+
+			body, _ := json.Marshal(restriction)
+			restrictions[fmt.Sprintf("%s/%d", restrictionsPath, nextID)] = string(body)
+			nextID++
+			w.Write(body)
+		case r.Method == "DELETE":
+			deleted = append(deleted, r.URL.Path)
+			delete(restrictions, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasPrefix(r.URL.Path, restrictionsPath+"/"):
+			testResponses(restrictions)(w, r)
+		default:
+			testResponses(responses)(w, r)
+		}
+	}))
+	defer closeServer()
+
+	r := resourceRepository()
+	state := &terraform.InstanceState{
+		ID: "test-owner/test-repo",
+		Attributes: map[string]string{
+			"owner":                        "test-owner",
+			"name":                         "test-repo",
+			"slug":                         "test-repo",
+			"scm":                          "git",
+			"fork_policy":                  "allow_forks",
+			"is_private":                   "true",
+			"branch_restriction.#":         "1",
+			"branch_restriction.0.id":      "5",
+			"branch_restriction.0.kind":    "push",
+			"branch_restriction.0.pattern": "master",
+		},
+	}
+
+	diff, err := r.Diff(state, testResourceConfig(t, map[string]interface{}{
+		"owner": "test-owner",
+		"name":  "test-repo",
+		"branch_restriction": []interface{}{
+			map[string]interface{}{
+				"kind":    "require_approvals_to_merge",
+				"pattern": "master",
+				"value":   2,
+			},
+		},
+	}), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	newState, err := r.Apply(state, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(deleted) != 1 || deleted[0] != restrictionsPath+"/5" {
+		t.Fatalf("expected the old restriction to be deleted, got %v", deleted)
+	}
+
+	expected := map[string]string{
+		"branch_restriction.#":         "1",
+		"branch_restriction.0.id":      "6",
+		"branch_restriction.0.kind":    "require_approvals_to_merge",
+		"branch_restriction.0.pattern": "master",
+		"branch_restriction.0.value":   "2",
+	}
+	for k, v := range expected {
+		if newState.Attributes[k] != v {
+			t.Errorf("expected %s to be %q, got %q", k, v, newState.Attributes[k])
+		}
+	}
+
+	// Removed outside of terraform
+	delete(restrictions, restrictionsPath+"/6")
+
+	d := r.Data(newState)
+	if err := resourceRepositoryRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if n := len(d.Get("branch_restriction").([]interface{})); n != 0 {
+		t.Fatalf("expected the removed restriction to be dropped, got %d", n)
+	}
+}
+
+func TestRepositoryCreate_fork(t *testing.T) {
+	repo := `{"name": "test-repo", "slug": "test-repo", "scm": "git", "fork_policy": "allow_forks", "is_private": true,
+		"parent": {"full_name": "upstream/Upstream-Repo"}}`
+	responses := testRepositoryResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo": repo,
+	})
+
+	var sent map[string]interface{}
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			if r.URL.Path != "/2.0/repositories/upstream/upstream-repo/forks" {
+				t.Errorf("expected the fork to be created from its parent, got %s %s", r.Method, r.URL.Path)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			json.NewDecoder(r.Body).Decode(&sent)
+			w.Write([]byte(repo))
+			return
+		}
+		testResponses(responses)(w, r)
+	}))
+	defer closeServer()
+
+	raw := map[string]interface{}{
+		"owner": "test-owner",
+		"name":  "test-repo",
+		"fork_of": []interface{}{map[string]interface{}{
+			"owner": "upstream",
+			"slug":  "upstream-repo",
+		}},
+	}
+
+	r := resourceRepository()
+	diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if workspace, ok := sent["workspace"].(map[string]interface{}); !ok || workspace["slug"] != "test-owner" || sent["name"] != "test-repo" {
+		t.Fatalf("expected the fork to be created in test-owner, sent %v", sent)
+	}
+	if state.ID != "test-owner/test-repo" || state.Attributes["parent_full_name"] != "upstream/Upstream-Repo" {
+		t.Fatalf("unexpected state %s %#v", state.ID, state.Attributes)
+	}
+
+	// The parent is matched regardless of casing like bitbucket does
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected no diff, got %#v", diff.Attributes)
+	}
+
+	// An imported fork reads its parent back so configuring it doesn't replace the repository
+	imported, err := r.Refresh(&terraform.InstanceState{ID: "test-owner/test-repo"}, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if imported.Attributes["fork_of.0.owner"] != "upstream" || imported.Attributes["fork_of.0.slug"] != "Upstream-Repo" {
+		t.Fatalf("expected fork_of to be read from the parent, got %#v", imported.Attributes)
+	}
+
+	raw["fork_of"] = []interface{}{map[string]interface{}{
+		"owner": "upstream",
+		"slug":  "other-repo",
+	}}
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.RequiresNew() {
+		t.Fatalf("expected forking another repository to replace it, got %#v", diff)
+	}
+}
+
+func TestRepositoryCreate_mainBranch(t *testing.T) {
+	// A fork comes with the branches of its parent
+	mainBranch := "main"
+
+	responses := testRepositoryResponses(map[string]string{})
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" && r.URL.Path == "/2.0/repositories/upstream/upstream-repo/forks" {
+			w.Write([]byte(`{"name": "test-repo", "slug": "test-repo"}`))
+			return
+		}
+		if r.URL.Path != "/2.0/repositories/test-owner/test-repo" {
+			testResponses(responses)(w, r)
+			return
+		}
+
+		if r.Method == "PUT" {
+			var payload Repository
+			json.NewDecoder(r.Body).Decode(&payload)
+			if payload.Mainbranch != nil {
+				mainBranch = payload.Mainbranch.Name
+			}
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": "test-repo", "slug": "test-repo", "scm": "git",
+			"fork_policy": "allow_forks", "is_private": true, "mainbranch": map[string]interface{}{"name": mainBranch},
+			"parent": map[string]interface{}{"full_name": "upstream/upstream-repo"}})
+	}))
+	defer closeServer()
+
+	raw := map[string]interface{}{
+		"owner":       "test-owner",
+		"name":        "test-repo",
+		"main_branch": "develop",
+		"fork_of": []interface{}{map[string]interface{}{
+			"owner": "upstream",
+			"slug":  "upstream-repo",
+		}},
+	}
+
+	r := resourceRepository()
+	diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if mainBranch != "develop" {
+		t.Fatalf("expected the main branch to be develop after create, got %q", mainBranch)
+	}
+
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected no diff, got %#v", diff.Attributes)
+	}
+}
+
+func TestRepositoryCreate_mainBranchOfEmptyRepository(t *testing.T) {
+	_, err := resourceRepository().Diff(nil, testResourceConfig(t, map[string]interface{}{
+		"owner":       "test-owner",
+		"name":        "test-repo",
+		"main_branch": "develop",
+	}), nil)
+
+	if err == nil || !strings.Contains(err.Error(), "has no branches yet") {
+		t.Fatalf("expected a no branches yet error, got %v", err)
+	}
+}
+
+func TestRepositoryCreate_idUsesCanonicalSlug(t *testing.T) {
+	repo := `{"name": "Test-Repo", "slug": "test-repo", "scm": "git", "fork_policy": "allow_forks", "is_private": true}`
+	responses := testRepositoryResponses(map[string]string{
+		// bitbucket answers for any casing of the slug
+		"/2.0/repositories/test-owner/Test-Repo": repo,
+		"/2.0/repositories/test-owner/test-repo": repo,
+	})
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.Write([]byte(repo))
+			return
+		}
+		testResponses(responses)(w, r)
+	}))
+	defer closeServer()
+
+	raw := map[string]interface{}{
+		"owner": "test-owner",
+		"name":  "Test-Repo",
+	}
+
+	r := resourceRepository()
+	diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if state.ID != "test-owner/test-repo" || state.Attributes["slug"] != "test-repo" {
+		t.Fatalf("expected the ID to use the canonical slug, got %s with slug %s", state.ID, state.Attributes["slug"])
+	}
+
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected no diff, got %#v", diff.Attributes)
+	}
+}
+
+func TestRepositoryRead_normalizesSlugInID(t *testing.T) {
+	repo := `{"name": "Test-Repo", "slug": "test-repo"}`
+	client, closeServer := testClient(t, testResponses(testRepositoryResponses(map[string]string{
+		"/2.0/repositories/test-owner/Test-Repo": repo,
+		"/2.0/repositories/test-owner/test-repo": repo,
+	})))
+	defer closeServer()
+
+	d := resourceRepository().Data(&terraform.InstanceState{ID: "test-owner/Test-Repo"})
+	if err := resourceRepositoryRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if d.Id() != "test-owner/test-repo" {
+		t.Fatalf("expected the ID to be normalized to the canonical slug, got %s", d.Id())
+	}
+}
+
+func TestRepositoryRead_renamedOutsideTerraform(t *testing.T) {
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/2.0/repositories/test-owner" {
+			if q := r.URL.Query().Get("q"); q != `uuid="{1234}"` {
+				t.Errorf("unexpected query %q", q)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(`{"values": [{"uuid": "{1234}", "name": "renamed-repo", "slug": "renamed-repo"}]}`))
+			return
+		}
+
+		testResponses(map[string]string{
+			"/2.0/repositories/test-owner/renamed-repo":                     `{"uuid": "{1234}", "name": "renamed-repo", "slug": "renamed-repo"}`,
+			"/2.0/repositories/test-owner/renamed-repo/pipelines_config":    `{"enabled": false}`,
+			"/2.0/repositories/test-owner/renamed-repo/branch-restrictions": `{"values": []}`,
+		})(w, r)
+	}))
+	defer closeServer()
+
+	d := resourceRepository().Data(&terraform.InstanceState{
+		ID: "test-owner/test-repo",
+		Attributes: map[string]string{
+			"owner": "test-owner",
+			"name":  "test-repo",
+			"slug":  "test-repo",
+			"uuid":  "{1234}",
+		},
+	})
+
+	if err := resourceRepositoryRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if d.Id() != "test-owner/renamed-repo" {
+		t.Fatalf("expected the ID to follow the rename, got %s", d.Id())
+	}
+	if v := d.Get("name").(string); v != "renamed-repo" {
+		t.Fatalf("expected name renamed-repo, got %s", v)
+	}
+}
+
+func TestRepositoryRead_serverError(t *testing.T) {
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer closeServer()
+
+	d := resourceRepository().Data(&terraform.InstanceState{
+		ID:         "test-owner/test-repo",
+		Attributes: map[string]string{"owner": "test-owner", "name": "test-repo", "slug": "test-repo"},
+	})
+
+	err := resourceRepositoryRead(d, client)
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("expected the 500 to be returned, got %v", err)
+	}
+	if d.Id() != "test-owner/test-repo" {
+		t.Fatalf("expected the repository to stay in state, got ID %q", d.Id())
+	}
+}
+
+func TestRepositoryRead_deletedOutsideTerraform(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(map[string]string{}))
+	defer closeServer()
+
+	d := resourceRepository().Data(&terraform.InstanceState{
+		ID:         "test-owner/test-repo",
+		Attributes: map[string]string{"owner": "test-owner", "name": "test-repo", "slug": "test-repo"},
+	})
+
+	if err := resourceRepositoryRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.Id() != "" {
+		t.Fatalf("expected the repository to be removed from state, got ID %q", d.Id())
+	}
+}
+
+func TestRepositoryImport_scmIsNotDrift(t *testing.T) {
+	cases := map[string]struct {
+		SCM         string
+		Config      string
+		RequiresNew bool
+	}{
+		"git repository": {
+			SCM: "git",
+		},
+		"legacy mercurial repository": {
+			SCM: "hg",
+		},
+		"configured scm matches": {
+			SCM:    "hg",
+			Config: "hg",
+		},
+		"configured scm differs": {
+			SCM:         "hg",
+			Config:      "git",
+			RequiresNew: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client, closeServer := testClient(t, testResponses(testRepositoryResponses(map[string]string{
+				"/2.0/repositories/test-owner/test-repo": fmt.Sprintf(`{"name": "test-repo", "slug": "test-repo", "scm": %q, "fork_policy": "allow_forks", "is_private": true}`, tc.SCM),
+			})))
+			defer closeServer()
+
+			r := resourceRepository()
+			imported, err := r.Importer.State(r.Data(&terraform.InstanceState{ID: "test-owner/test-repo"}), client)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			state, err := r.Refresh(imported[0].State(), client)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if state.Attributes["scm"] != tc.SCM {
+				t.Fatalf("expected scm %s to be read, got %q", tc.SCM, state.Attributes["scm"])
+			}
+
+			raw := map[string]interface{}{
+				"owner": "test-owner",
+				"name":  "test-repo",
+			}
+			if tc.Config != "" {
+				raw["scm"] = tc.Config
+			}
+
+			diff, err := r.Diff(state, testResourceConfig(t, raw), client)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if !tc.RequiresNew {
+				if !diff.Empty() {
+					t.Fatalf("expected no diff, got %#v", diff.Attributes)
+				}
+				return
+			}
+			if _, ok := diff.Attributes["scm"]; !ok || !diff.RequiresNew() {
+				t.Fatalf("expected changing scm to replace the repository, got %#v", diff)
+			}
+		})
+	}
+}
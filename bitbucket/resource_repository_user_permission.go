@@ -0,0 +1,154 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+func resourceRepositoryUserPermission() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceRepositoryUserPermissionPut,
+		Read:   resourceRepositoryUserPermissionRead,
+		Update: resourceRepositoryUserPermissionPut,
+		Delete: resourceRepositoryUserPermissionDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"workspace": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"repo_slug": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"user_uuid": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"email"},
+			},
+			"email": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"user_uuid"},
+			},
+			"permission": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice([]string{"read", "write", "admin"}, false),
+			},
+		},
+	}
+}
+
+func repositoryUserPermissionURL(workspace, repoSlug, userUUID string) string {
+	return fmt.Sprintf("repositories/%s/%s/permissions-config/users/%s",
+		workspace,
+		repoSlug,
+		url.PathEscape(userUUID),
+	)
+}
+
+// resourceRepositoryUserPermissionPut grants the permission, the same PUT replaces the permission
+// the user had so it both creates and updates
+func resourceRepositoryUserPermissionPut(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	// The email is only looked up when the permission is first given, the uuid is kept after that
+	if d.Id() == "" && d.Get("user_uuid").(string) == "" {
+		if d.Get("email").(string) == "" {
+			return fmt.Errorf("One of user_uuid or email must be set")
+		}
+
+		user, err := resolveUserByEmail(client, d.Get("workspace").(string), d.Get("email").(string))
+		if err != nil {
+			return err
+		}
+		d.Set("user_uuid", user.UUID)
+	}
+
+	bytedata, err := json.Marshal(map[string]interface{}{
+		"permission": d.Get("permission").(string),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Put(repositoryUserPermissionURL(
+		d.Get("workspace").(string),
+		d.Get("repo_slug").(string),
+		d.Get("user_uuid").(string),
+	), bytes.NewBuffer(bytedata))
+
+	if err != nil {
+		return fmt.Errorf("Failed to give %s %s permission on %s/%s: %s", d.Get("user_uuid").(string),
+			d.Get("permission").(string), d.Get("workspace").(string), d.Get("repo_slug").(string), err)
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s/%s",
+		d.Get("workspace").(string),
+		d.Get("repo_slug").(string),
+		d.Get("user_uuid").(string),
+	))
+
+	return resourceRepositoryUserPermissionRead(d, m)
+}
+
+func resourceRepositoryUserPermissionRead(d *schema.ResourceData, m interface{}) error {
+	idparts := strings.SplitN(d.Id(), "/", 3)
+	if len(idparts) != 3 {
+		return fmt.Errorf("Incorrect ID format, should match `workspace/repo_slug/user_uuid`")
+	}
+
+	d.Set("workspace", idparts[0])
+	d.Set("repo_slug", idparts[1])
+	d.Set("user_uuid", idparts[2])
+
+	client := m.(*Client)
+	permissionReq, err := client.Get(repositoryUserPermissionURL(idparts[0], idparts[1], idparts[2]))
+
+	// The permission was taken away outside of terraform, so it is granted again
+	if permissionReq != nil && permissionReq.StatusCode == 404 {
+		log.Printf("[WARN] Repository user permission %s not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var permission UserPermission
+
+	decodeerr := json.NewDecoder(permissionReq.Body).Decode(&permission)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	d.Set("permission", permission.Permission)
+
+	return nil
+}
+
+func resourceRepositoryUserPermissionDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+	return client.DeleteIgnoringNotFound(repositoryUserPermissionURL(
+		d.Get("workspace").(string),
+		d.Get("repo_slug").(string),
+		d.Get("user_uuid").(string),
+	))
+}
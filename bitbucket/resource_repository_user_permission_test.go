@@ -0,0 +1,169 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestRepositoryUserPermission_lifecycle(t *testing.T) {
+	permission := ""
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2.0/repositories/test-owner/test-repo/permissions-config/users/{gob}" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		switch r.Method {
+		case "PUT":
+			var sent UserPermission
+			json.NewDecoder(r.Body).Decode(&sent)
+			permission = sent.Permission
+		case "DELETE":
+			permission = ""
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if permission == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"type": "repository_user_permission", "permission": "` + permission + `", "user": {"uuid": "{gob}"}}`))
+	}))
+	defer closeServer()
+
+	raw := func(permission string) map[string]interface{} {
+		return map[string]interface{}{
+			"workspace":  "test-owner",
+			"repo_slug":  "test-repo",
+			"user_uuid":  "{gob}",
+			"permission": permission,
+		}
+	}
+
+	r := resourceRepositoryUserPermission()
+	var state *terraform.InstanceState
+
+	for _, level := range []string{"write", "admin"} {
+		diff, err := r.Diff(state, testResourceConfig(t, raw(level)), client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if state, err = r.Apply(state, diff, client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if permission != level || state.ID != "test-owner/test-repo/{gob}" || state.Attributes["permission"] != level {
+			t.Fatalf("expected %s permission, got %q and state %#v", level, permission, state)
+		}
+	}
+
+	// Changed outside of terraform
+	permission = "read"
+
+	state, err := r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err := r.Diff(state, testResourceConfig(t, raw("admin")), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff.Empty() || diff.RequiresNew() {
+		t.Fatalf("expected the permission to be updated in place, got %#v", diff)
+	}
+
+	if _, err := r.Apply(state, &terraform.InstanceDiff{Destroy: true}, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if permission != "" {
+		t.Fatalf("expected the permission to be removed, got %q", permission)
+	}
+}
+
+func TestRepositoryUserPermission_createByEmail(t *testing.T) {
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2.0/workspaces/test-owner/members":
+			w.Write([]byte(`{"values": [{"user": {"uuid": "{gob}"}}]}`))
+		case "/2.0/repositories/test-owner/test-repo/permissions-config/users/{gob}":
+			w.Write([]byte(`{"permission": "write", "user": {"uuid": "{gob}"}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer closeServer()
+
+	r := resourceRepositoryUserPermission()
+	raw := map[string]interface{}{
+		"workspace":  "test-owner",
+		"repo_slug":  "test-repo",
+		"email":      "gob@example.com",
+		"permission": "write",
+	}
+
+	diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if state.ID != "test-owner/test-repo/{gob}" || state.Attributes["user_uuid"] != "{gob}" {
+		t.Fatalf("expected the permission to be given to the uuid of the email, got %#v", state)
+	}
+
+	state, err = r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff, _ := r.Diff(state, testResourceConfig(t, raw), client); diff != nil && !diff.Empty() {
+		t.Fatalf("expected no changes, got %#v", diff)
+	}
+}
+
+func TestRepositoryUserPermission_import(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(map[string]string{
+		"/2.0/repositories/test-owner/test-repo/permissions-config/users/{gob}": `{"permission": "write", "user": {"uuid": "{gob}"}}`,
+	}))
+	defer closeServer()
+
+	d := resourceRepositoryUserPermission().Data(&terraform.InstanceState{ID: "test-owner/test-repo/{gob}"})
+	if err := resourceRepositoryUserPermissionRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for key, expected := range map[string]string{
+		"workspace":  "test-owner",
+		"repo_slug":  "test-repo",
+		"user_uuid":  "{gob}",
+		"permission": "write",
+	} {
+		if v := d.Get(key).(string); v != expected {
+			t.Fatalf("expected %s to be %q, got %q", key, expected, v)
+		}
+	}
+}
+
+func TestRepositoryUserPermission_removedOutsideOfTerraform(t *testing.T) {
+	client, closeServer := testClient(t, testResponses(nil))
+	defer closeServer()
+
+	d := resourceRepositoryUserPermission().Data(&terraform.InstanceState{ID: "test-owner/test-repo/{gob}"})
+	if err := resourceRepositoryUserPermissionRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.Id() != "" {
+		t.Fatalf("expected the permission to be removed from state, got %s", d.Id())
+	}
+}
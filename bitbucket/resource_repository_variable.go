@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
-	"net/url"
 
 	"github.com/hashicorp/terraform/helper/schema"
 )
@@ -36,22 +34,38 @@ func resourceRepositoryVariable() *schema.Resource {
 				Required: true,
 			},
 			"value": {
-				Type:     schema.TypeString,
-				Required: true,
+				Type:      schema.TypeString,
+				Required:  true,
+				Sensitive: true,
 			},
 			"secured": {
 				Type:     schema.TypeBool,
 				Optional: true,
 				Default:  true,
 			},
+			"owner": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
 			"repository": {
 				Type:     schema.TypeString,
 				Required: true,
+				ForceNew: true,
 			},
 		},
 	}
 }
 
+// repositoryVariableRepository returns the owner/slug of the repository the variable belongs to,
+// without an owner the repository is expected to be the ID of a bitbucket_repository already
+func repositoryVariableRepository(d *schema.ResourceData) string {
+	if owner := d.Get("owner").(string); owner != "" {
+		return fmt.Sprintf("%s/%s", owner, d.Get("repository").(string))
+	}
+	return d.Get("repository").(string)
+}
+
 func newRepositoryVariableFromResource(d *schema.ResourceData) *RepositoryVariable {
 	dk := &RepositoryVariable{
 		Key:     d.Get("key").(string),
@@ -70,8 +84,8 @@ func resourceRepositoryVariableCreate(d *schema.ResourceData, m interface{}) err
 	if err != nil {
 		return err
 	}
-	req, err := client.Post(fmt.Sprintf("2.0/repositories/%s/pipelines_config/variables/",
-		d.Get("repository").(string),
+	req, err := client.Post(fmt.Sprintf("repositories/%s/pipelines_config/variables/",
+		repositoryVariableRepository(d),
 	), bytes.NewBuffer(bytedata))
 
 	if err != nil {
@@ -98,12 +112,20 @@ func resourceRepositoryVariableCreate(d *schema.ResourceData, m interface{}) err
 func resourceRepositoryVariableRead(d *schema.ResourceData, m interface{}) error {
 
 	client := m.(*Client)
-	rvReq, _ := client.Get(fmt.Sprintf("2.0/repositories/%s/pipelines_config/variables/%s",
-		d.Get("repository").(string),
+	rvReq, err := client.Get(fmt.Sprintf("repositories/%s/pipelines_config/variables/%s",
+		repositoryVariableRepository(d),
 		d.Get("uuid").(string),
 	))
 
-	log.Printf("ID: %s", url.PathEscape(d.Id()))
+	if rvReq != nil && rvReq.StatusCode == 404 {
+		d.SetId("")
+		return nil
+	}
+
+	// Still failing once the retries ran out
+	if err != nil {
+		return err
+	}
 
 	if rvReq.StatusCode == 200 {
 		var rv RepositoryVariable
@@ -119,13 +141,12 @@ func resourceRepositoryVariableRead(d *schema.ResourceData, m interface{}) error
 
 		d.Set("uuid", rv.UUID)
 		d.Set("key", rv.Key)
-		d.Set("value", rv.Value)
 		d.Set("secured", rv.Secured)
-	}
 
-	if rvReq.StatusCode == 404 {
-		d.SetId("")
-		return nil
+		// Bitbucket never returns the value of a secured variable, the configured one is kept
+		if !rv.Secured {
+			d.Set("value", rv.Value)
+		}
 	}
 
 	return nil
@@ -139,8 +160,8 @@ func resourceRepositoryVariableUpdate(d *schema.ResourceData, m interface{}) err
 	if err != nil {
 		return err
 	}
-	req, err := client.Put(fmt.Sprintf("2.0/repositories/%s/pipelines_config/variables/%s",
-		d.Get("repository").(string),
+	req, err := client.Put(fmt.Sprintf("repositories/%s/pipelines_config/variables/%s",
+		repositoryVariableRepository(d),
 		d.Get("uuid").(string),
 	), bytes.NewBuffer(bytedata))
 
@@ -157,9 +178,8 @@ func resourceRepositoryVariableUpdate(d *schema.ResourceData, m interface{}) err
 
 func resourceRepositoryVariableDelete(d *schema.ResourceData, m interface{}) error {
 	client := m.(*Client)
-	_, err := client.Delete(fmt.Sprintf(fmt.Sprintf("2.0/repositories/%s/pipelines_config/variables/%s",
-		d.Get("repository").(string),
+	return client.DeleteIgnoringNotFound(fmt.Sprintf("repositories/%s/pipelines_config/variables/%s",
+		repositoryVariableRepository(d),
 		d.Get("uuid").(string),
-	)))
-	return err
+	))
 }
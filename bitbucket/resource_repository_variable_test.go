@@ -1,7 +1,9 @@
 package bitbucket
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"testing"
 
@@ -68,3 +70,85 @@ func testAccCheckBitbucketRepositoryVariableExists(n, key, value string) resourc
 		return nil
 	}
 }
+
+func TestRepositoryVariable_securedValueIsNotDrift(t *testing.T) {
+	var stored RepositoryVariable
+	var updated bool
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const variablesPath = "/2.0/repositories/test-owner/test-repo/pipelines_config/variables/"
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == variablesPath:
+			json.NewDecoder(r.Body).Decode(&stored)
+			stored.UUID = "{variable}"
+		case r.Method == "PUT" && r.URL.Path == variablesPath+"{variable}":
+			json.NewDecoder(r.Body).Decode(&stored)
+			stored.UUID = "{variable}"
+			updated = true
+		case r.Method == "GET" && r.URL.Path == variablesPath+"{variable}":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// The value of a secured variable is never sent back
+		response := stored
+		if response.Secured {
+			response.Value = ""
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer closeServer()
+
+	raw := map[string]interface{}{
+		"owner":      "test-owner",
+		"repository": "test-repo",
+		"key":        "TOKEN",
+		"value":      "secret",
+		"secured":    true,
+	}
+
+	r := resourceRepositoryVariable()
+	diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if stored.Value != "secret" || state.Attributes["uuid"] != "{variable}" {
+		t.Fatalf("unexpected variable %#v with state %#v", stored, state.Attributes)
+	}
+
+	state, err = r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if state.Attributes["value"] != "secret" {
+		t.Fatalf("expected the configured value to be kept, got %q", state.Attributes["value"])
+	}
+
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected no diff, got %#v", diff.Attributes)
+	}
+
+	raw["value"] = "rotated"
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := r.Apply(state, diff, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !updated || stored.Value != "rotated" {
+		t.Fatalf("expected the value to be updated in place, got %#v", stored)
+	}
+}
@@ -0,0 +1,297 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// PaginatedHooks is a paginated list of hooks that the bitbucket api returns
+type PaginatedHooks struct {
+	Values []Hook `json:"values,omitempty"`
+	Page   int    `json:"page,omitempty"`
+	Size   int    `json:"size,omitempty"`
+	Next   string `json:"next,omitempty"`
+}
+
+func resourceRepositoryWebhooks() *schema.Resource {
+	return &schema.Resource{
+		Create:        resourceRepositoryWebhooksCreate,
+		Read:          resourceRepositoryWebhooksRead,
+		Update:        resourceRepositoryWebhooksUpdate,
+		Delete:        resourceRepositoryWebhooksDelete,
+		CustomizeDiff: resourceRepositoryWebhooksCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"owner": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"repository": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"ignore_external": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"description_prefix": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"webhook": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"url": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"description": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"active": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  true,
+						},
+						"events": {
+							Type:     schema.TypeSet,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+							Set:      schema.HashString,
+						},
+						"skip_cert_verification": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceRepositoryWebhooksCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	if d.HasChange("webhook") && d.NewValueKnown("webhook") {
+		var events []string
+		for _, hook := range expandWebhooks(d.Get("webhook")) {
+			events = append(events, hook.Events...)
+		}
+
+		if err := validateWebhookEvents(m, "repository", events); err != nil {
+			return err
+		}
+	}
+
+	prefix, err := managedPrefix(d, "ignore_external", "description_prefix")
+	if err != nil || prefix == "" {
+		return err
+	}
+
+	for _, hook := range expandWebhooks(d.Get("webhook")) {
+		if hook.Description != "" && !strings.HasPrefix(hook.Description, prefix) {
+			return fmt.Errorf("webhook %q must have a description starting with %q when ignore_external is true",
+				hook.Description, prefix)
+		}
+	}
+
+	return nil
+}
+
+func expandWebhook(m map[string]interface{}) Hook {
+	events := make([]string, 0, len(m["events"].(*schema.Set).List()))
+	for _, event := range m["events"].(*schema.Set).List() {
+		events = append(events, event.(string))
+	}
+
+	return Hook{
+		URL:                  m["url"].(string),
+		Description:          m["description"].(string),
+		Active:               m["active"].(bool),
+		SkipCertVerification: m["skip_cert_verification"].(bool),
+		Events:               events,
+	}
+}
+
+func expandWebhooks(v interface{}) []Hook {
+	hooks := make([]Hook, 0, len(v.(*schema.Set).List()))
+
+	for _, item := range v.(*schema.Set).List() {
+		hooks = append(hooks, expandWebhook(item.(map[string]interface{})))
+	}
+
+	return hooks
+}
+
+func flattenWebhooks(hooks []Hook) []map[string]interface{} {
+	flattened := make([]map[string]interface{}, 0, len(hooks))
+
+	for _, hook := range hooks {
+		flattened = append(flattened, map[string]interface{}{
+			"url":                    hook.URL,
+			"description":            hook.Description,
+			"active":                 hook.Active,
+			"skip_cert_verification": hook.SkipCertVerification,
+			"events":                 hook.Events,
+		})
+	}
+
+	return flattened
+}
+
+// webhookKey identifies a webhook by everything we manage about it, bitbucket hooks have no
+// natural key so a hook that differs in any way is replaced
+func webhookKey(hook Hook) string {
+	events := append([]string{}, hook.Events...)
+	sort.Strings(events)
+
+	return fmt.Sprintf("%s|%s|%t|%t|%s",
+		hook.URL,
+		hook.Description,
+		hook.Active,
+		hook.SkipCertVerification,
+		strings.Join(events, ","),
+	)
+}
+
+func getWebhooks(client *Client, owner, repoSlug string) ([]Hook, error) {
+	values, err := getRepositoryCollection(client, owner, repoSlug, "hooks")
+	if err != nil {
+		return nil, err
+	}
+
+	hooks := make([]Hook, 0, len(values))
+	for _, value := range values {
+		var hook Hook
+		if err := json.Unmarshal(value, &hook); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	return hooks, nil
+}
+
+// getManagedWebhooks returns the webhooks on the repository this resource owns, that is all of
+// them unless ignore_external limits it to the ones with the description prefix
+func getManagedWebhooks(d *schema.ResourceData, client *Client) ([]Hook, error) {
+	hooks, err := getWebhooks(client, d.Get("owner").(string), d.Get("repository").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	managed := make([]Hook, 0, len(hooks))
+	for _, hook := range hooks {
+		if isManaged(d, "ignore_external", "description_prefix", hook.Description) {
+			managed = append(managed, hook)
+		}
+	}
+
+	return managed, nil
+}
+
+func webhookKeys(hooks []Hook) []string {
+	keys := make([]string, 0, len(hooks))
+	for _, hook := range hooks {
+		keys = append(keys, webhookKey(hook))
+	}
+
+	return keys
+}
+
+// reconcileWebhooks deletes every managed webhook that isn't in the config and creates the missing ones
+func reconcileWebhooks(d *schema.ResourceData, client *Client, desired []Hook) error {
+	owner := d.Get("owner").(string)
+	repoSlug := d.Get("repository").(string)
+
+	existing, err := getManagedWebhooks(d, client)
+	if err != nil {
+		return err
+	}
+
+	remove := func(i int) error {
+		err := client.DeleteIgnoringNotFound(fmt.Sprintf("repositories/%s/%s/hooks/%s",
+			owner,
+			repoSlug,
+			url.PathEscape(existing[i].UUID),
+		))
+
+		if err != nil {
+			return fmt.Errorf("Failed to delete webhook %s: %s", existing[i].URL, err)
+		}
+
+		return nil
+	}
+
+	create := func(i int) error {
+		payload, err := json.Marshal(desired[i])
+		if err != nil {
+			return err
+		}
+
+		_, err = client.Post(fmt.Sprintf("repositories/%s/%s/hooks",
+			owner,
+			repoSlug,
+		), bytes.NewBuffer(payload))
+
+		if err != nil {
+			return fmt.Errorf("Failed to create webhook %s: %s", desired[i].URL, err)
+		}
+
+		return nil
+	}
+
+	return reconcile(webhookKeys(existing), webhookKeys(desired), remove, create)
+}
+
+func resourceRepositoryWebhooksCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	if err := reconcileWebhooks(d, client, expandWebhooks(d.Get("webhook"))); err != nil {
+		return err
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", d.Get("owner").(string), d.Get("repository").(string)))
+
+	return resourceRepositoryWebhooksRead(d, m)
+}
+
+func resourceRepositoryWebhooksRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	hooks, err := getManagedWebhooks(d, client)
+	if err != nil {
+		return err
+	}
+
+	d.Set("webhook", flattenWebhooks(hooks))
+
+	return nil
+}
+
+func resourceRepositoryWebhooksUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	if err := reconcileWebhooks(d, client, expandWebhooks(d.Get("webhook"))); err != nil {
+		return err
+	}
+
+	return resourceRepositoryWebhooksRead(d, m)
+}
+
+func resourceRepositoryWebhooksDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+	return reconcileWebhooks(d, client, nil)
+}
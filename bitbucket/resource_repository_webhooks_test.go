@@ -0,0 +1,131 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// testWebhooksServer keeps webhooks in memory so reconciling can be checked end to end
+type testWebhooksServer struct {
+	hooks  map[string]Hook
+	nextID int
+}
+
+func (s *testWebhooksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	hooksPath := "/2.0/repositories/test-owner/test-repo/hooks"
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == hooksPath:
+		var page PaginatedHooks
+		for _, hook := range s.hooks {
+			page.Values = append(page.Values, hook)
+		}
+		json.NewEncoder(w).Encode(page)
+	case r.Method == "POST" && r.URL.Path == hooksPath:
+		var hook Hook
+		json.NewDecoder(r.Body).Decode(&hook)
+		s.nextID++
+		hook.UUID = fmt.Sprintf("{%d}", s.nextID)
+		s.hooks[hook.UUID] = hook
+		json.NewEncoder(w).Encode(hook)
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, hooksPath+"/"):
+		delete(s.hooks, strings.TrimPrefix(r.URL.Path, hooksPath+"/"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"type": "error", "error": {"message": "Not found"}}`))
+	}
+}
+
+func (s *testWebhooksServer) urls() []string {
+	var urls []string
+	for _, hook := range s.hooks {
+		urls = append(urls, hook.URL)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func TestRepositoryWebhooks_reconcile(t *testing.T) {
+	cases := map[string]struct {
+		IgnoreExternal bool
+		Expected       []string
+	}{
+		"enforce deletes every external webhook": {
+			Expected: []string{"https://a.example.com", "https://d.example.com"},
+		},
+		"ignore_external only manages the prefix": {
+			IgnoreExternal: true,
+			Expected:       []string{"https://a.example.com", "https://b.example.com", "https://d.example.com"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := &testWebhooksServer{
+				nextID: 3,
+				hooks: map[string]Hook{
+					"{1}": {UUID: "{1}", URL: "https://a.example.com", Description: "tf: a", Active: true, SkipCertVerification: true, Events: []string{"repo:push"}},
+					"{2}": {UUID: "{2}", URL: "https://b.example.com", Description: "added by hand", Active: true, Events: []string{"repo:push"}},
+					"{3}": {UUID: "{3}", URL: "https://c.example.com", Description: "tf: old", Active: true, Events: []string{"repo:push"}},
+				},
+			}
+
+			client, closeServer := testClient(t, server)
+			defer closeServer()
+
+			d := schema.TestResourceDataRaw(t, resourceRepositoryWebhooks().Schema, map[string]interface{}{
+				"owner":              "test-owner",
+				"repository":         "test-repo",
+				"ignore_external":    tc.IgnoreExternal,
+				"description_prefix": "tf: ",
+				"webhook": []interface{}{
+					map[string]interface{}{
+						"url":         "https://a.example.com",
+						"description": "tf: a",
+						"events":      []interface{}{"repo:push"},
+					},
+					map[string]interface{}{
+						"url":         "https://d.example.com",
+						"description": "tf: d",
+						"events":      []interface{}{"repo:push", "pullrequest:created"},
+					},
+				},
+			})
+
+			if err := resourceRepositoryWebhooksCreate(d, client); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if urls := server.urls(); strings.Join(urls, ",") != strings.Join(tc.Expected, ",") {
+				t.Fatalf("expected webhooks %v, got %v", tc.Expected, urls)
+			}
+
+			if _, ok := server.hooks["{1}"]; !ok {
+				t.Fatal("expected the unchanged webhook to be kept rather than recreated")
+			}
+
+			if n := d.Get("webhook").(*schema.Set).Len(); n != 2 {
+				t.Fatalf("expected 2 managed webhooks in state, got %d", n)
+			}
+
+			if err := resourceRepositoryWebhooksDelete(d, client); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			remaining := len(server.hooks)
+			if tc.IgnoreExternal && remaining != 1 {
+				t.Fatalf("expected the external webhook to survive delete, got %v", server.urls())
+			}
+			if !tc.IgnoreExternal && remaining != 0 {
+				t.Fatalf("expected every webhook to be deleted, got %v", server.urls())
+			}
+		})
+	}
+}
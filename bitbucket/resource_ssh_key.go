@@ -0,0 +1,159 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// SSHKey is an ssh key that authenticates as a user
+type SSHKey struct {
+	UUID     string `json:"uuid,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Label    string `json:"label,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+	LastUsed string `json:"last_used,omitempty"`
+}
+
+func resourceSSHKey() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceSSHKeyCreate,
+		Read:   resourceSSHKeyRead,
+		Update: resourceSSHKeyUpdate,
+		Delete: resourceSSHKeyDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"user": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"key": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSSHKeyDiff,
+			},
+			"label": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"uuid": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"comment": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"last_used": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func sshKeyURL(user, uuid string) string {
+	return fmt.Sprintf("users/%s/ssh-keys/%s", url.PathEscape(user), url.PathEscape(uuid))
+}
+
+func resourceSSHKeyCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	bytedata, err := json.Marshal(&SSHKey{
+		Key:   d.Get("key").(string),
+		Label: d.Get("label").(string),
+	})
+	if err != nil {
+		return err
+	}
+
+	sshKeyReq, err := client.Post(fmt.Sprintf("users/%s/ssh-keys",
+		url.PathEscape(d.Get("user").(string)),
+	), bytes.NewBuffer(bytedata))
+
+	if err != nil {
+		return err
+	}
+
+	var sshKey SSHKey
+
+	decodeerr := json.NewDecoder(sshKeyReq.Body).Decode(&sshKey)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", d.Get("user").(string), sshKey.UUID))
+
+	return resourceSSHKeyRead(d, m)
+}
+
+func resourceSSHKeyRead(d *schema.ResourceData, m interface{}) error {
+	idparts := strings.Split(d.Id(), "/")
+	if len(idparts) != 2 {
+		return fmt.Errorf("Incorrect ID format, should match `user/uuid`")
+	}
+
+	d.Set("user", idparts[0])
+
+	client := m.(*Client)
+	sshKeyReq, err := client.Get(sshKeyURL(idparts[0], idparts[1]))
+
+	// The key was removed outside of terraform
+	if sshKeyReq != nil && sshKeyReq.StatusCode == 404 {
+		log.Printf("[WARN] SSH key %s not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var sshKey SSHKey
+
+	decodeerr := json.NewDecoder(sshKeyReq.Body).Decode(&sshKey)
+	if decodeerr != nil {
+		return decodeerr
+	}
+
+	d.Set("uuid", sshKey.UUID)
+	d.Set("key", normalizeSSHKey(sshKey.Key))
+	d.Set("label", sshKey.Label)
+	d.Set("comment", sshKey.Comment)
+	d.Set("last_used", formatTimestamp(sshKey.LastUsed))
+
+	return nil
+}
+
+func resourceSSHKeyUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+
+	bytedata, err := json.Marshal(&SSHKey{
+		Label: d.Get("label").(string),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Put(sshKeyURL(d.Get("user").(string), d.Get("uuid").(string)), bytes.NewBuffer(bytedata))
+	if err != nil {
+		return err
+	}
+
+	return resourceSSHKeyRead(d, m)
+}
+
+func resourceSSHKeyDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Client)
+	return client.DeleteIgnoringNotFound(sshKeyURL(d.Get("user").(string), d.Get("uuid").(string)))
+}
@@ -0,0 +1,99 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestSSHKey_lifecycle(t *testing.T) {
+	var stored SSHKey
+
+	client, closeServer := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const keysPath = "/2.0/users/automation/ssh-keys"
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == keysPath:
+			json.NewDecoder(r.Body).Decode(&stored)
+			stored.UUID = "{key}"
+		case r.Method == "PUT" && r.URL.Path == keysPath+"/{key}":
+			var update SSHKey
+			json.NewDecoder(r.Body).Decode(&update)
+			stored.Label = update.Label
+		case r.Method == "GET" && r.URL.Path == keysPath+"/{key}":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// Bitbucket splits the comment off and pads the key differently than it was given
+		response := stored
+		response.Key = "  " + normalizeSSHKey(stored.Key) + "  "
+		response.Comment = "automation@example.com"
+		response.LastUsed = "2020-01-23T09:21:35.000000+00:00"
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer closeServer()
+
+	raw := map[string]interface{}{
+		"user":  "automation",
+		"key":   testDeployKey,
+		"label": "ci",
+	}
+
+	r := resourceSSHKey()
+	diff, err := r.Diff(nil, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := r.Apply(nil, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if state.ID != "automation/{key}" || state.Attributes["comment"] != "automation@example.com" ||
+		state.Attributes["last_used"] != "2020-01-23T09:21:35Z" {
+		t.Fatalf("unexpected state %#v", state.Attributes)
+	}
+
+	state, err = r.Refresh(state, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected no diff, got %#v", diff.Attributes)
+	}
+
+	// Relabelling updates the key in place
+	raw["label"] = "deploys"
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff.RequiresNew() {
+		t.Fatal("expected a new label not to replace the key")
+	}
+	state, err = r.Apply(state, diff, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if stored.Label != "deploys" {
+		t.Fatalf("expected the label to be updated, got %q", stored.Label)
+	}
+
+	// A different key replaces it
+	raw["key"] = testDeployKeyRotated
+	diff, err = r.Diff(state, testResourceConfig(t, raw), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !diff.RequiresNew() {
+		t.Fatal("expected a rotated key to replace the key")
+	}
+}